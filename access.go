@@ -0,0 +1,151 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Returns the option with the given field or flag name, or an error if there
+// is no such option
+func (this *OptionSet) lookupOrError(name string) (*Option, error) {
+	opt := this.Lookup(name)
+
+	if opt == nil {
+		return nil, errors.New(fmt.Sprintf("Unknown option '%s'", name))
+	}
+
+	return opt, nil
+}
+
+// Returns the current value of the option with the given field name (i.e.
+// "Verbose") or flag name (i.e. "-v" or "--verbose")
+func (this *OptionSet) Get(name string) (interface{}, error) {
+	opt, err := this.lookupOrError(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return opt.Value(), nil
+}
+
+// Returns the current value of the string option with the given field or
+// flag name
+func (this *OptionSet) GetString(name string) (string, error) {
+	value, err := this.getKind(name, "a string", reflect.String)
+
+	if err != nil {
+		return "", err
+	}
+
+	return value.String(), nil
+}
+
+// Returns the current value of the int option with the given field or flag
+// name. Options of any signed integer type, except durations, can be read.
+func (this *OptionSet) GetInt(name string) (int, error) {
+	value, err := this.getKind(name, "an int", reflect.Int, reflect.Int8,
+		reflect.Int16, reflect.Int32, reflect.Int64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(value.Int()), nil
+}
+
+// Returns the current value of the duration option with the given field or
+// flag name
+func (this *OptionSet) GetDuration(name string) (time.Duration, error) {
+	opt, err := this.lookupOrError(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	value := reflect.ValueOf(opt.pointer).Elem()
+
+	if value.Type() != durationType {
+		return 0, errors.New(fmt.Sprintf("Option '%s' is not a duration", name))
+	}
+
+	return time.Duration(value.Int()), nil
+}
+
+// Returns the current value of the option with the given field or flag name,
+// if it is of one of the given kinds. Durations are never returned.
+func (this *OptionSet) getKind(name, description string, kinds ...reflect.Kind) (reflect.Value, error) {
+	opt, err := this.lookupOrError(name)
+
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	value := reflect.ValueOf(opt.pointer).Elem()
+
+	for _, kind := range kinds {
+		if value.Kind() == kind && value.Type() != durationType {
+			return value, nil
+		}
+	}
+
+	return reflect.Value{}, errors.New(fmt.Sprintf(
+		"Option '%s' is not %s",
+		name,
+		description))
+}
+
+// Sets the option with the given field or flag name to the given raw value,
+// parsed and validated the same way as flags and positional args. Values are
+// appended to slices, and secret values given as "-" are stored as is, not
+// read from the standard input. Options set this way are not changed by
+// reloads.
+func (this *OptionSet) Set(name, raw string) error {
+	opt, err := this.lookupOrError(name)
+
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	value := reflect.ValueOf(opt.pointer).Elem()
+	previous := reflect.New(value.Type()).Elem()
+	previous.Set(value)
+
+	if opt.IsPositional() {
+		err = opt.store(raw)
+	} else if flagName := strings.TrimLeft(name, "-"); this.names[flagName] == opt {
+		err = this.setLiteral(flagName, raw)
+	} else {
+		err = this.setLiteral(opt.FlagNames()[0], raw)
+	}
+
+	if err == nil {
+		err = opt.checkValue()
+	} else {
+		err = optionValueError(opt, raw, err)
+	}
+
+	// flag values may be changed even if they cannot be parsed
+	if err != nil {
+		value.Set(previous)
+		return err
+	}
+
+	opt.Source = SourceSet
+	this.publish()
+	return nil
+}
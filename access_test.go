@@ -0,0 +1,140 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestAccessStruct struct {
+	Name     string                   `long:"name" short:"n" default:"foo"`
+	Count    int64                    `short:"c" default:"2"`
+	Timeout  time.Duration            `long:"timeout" default:"1s"`
+	Level    string                   `long:"level" choices:"debug,info" default:"info"`
+	Color    bool                     `long:"color" negatable:"true" default:"true"`
+	Pin      int                      `long:"pin" secret:"true"`
+	Database TestAccessDatabaseStruct `group:"database"`
+	Files    []string                 `positional:"true"`
+}
+
+type TestAccessDatabaseStruct struct {
+	Host string `long:"db-host" default:"localhost"`
+}
+
+func accessTestNewSet(t *testing.T) (*OptionSet, *TestAccessStruct) {
+	opts := TestAccessStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	return set, &opts
+}
+
+func TestOptionSetGet(t *testing.T) {
+	set, _ := accessTestNewSet(t)
+	require.Nil(t, set.Parse([]string{"-n", "bar", "a.txt"}))
+
+	for _, name := range []string{"Name", "name", "-n", "--name"} {
+		value, err := set.Get(name)
+		require.Nil(t, err)
+		require.Equal(t, "bar", value)
+	}
+
+	value, err := set.Get("Database.Host")
+	require.Nil(t, err)
+	require.Equal(t, "localhost", value)
+
+	value, err = set.Get("Files")
+	require.Nil(t, err)
+	require.Equal(t, []string{"a.txt"}, value)
+
+	_, err = set.Get("nope")
+	require.Equal(t, "Unknown option 'nope'", err.Error())
+}
+
+func TestOptionSetGet_Typed(t *testing.T) {
+	set, _ := accessTestNewSet(t)
+
+	name, err := set.GetString("-n")
+	require.Nil(t, err)
+	require.Equal(t, "foo", name)
+
+	count, err := set.GetInt("c")
+	require.Nil(t, err)
+	require.Equal(t, 2, count)
+
+	timeout, err := set.GetDuration("--timeout")
+	require.Nil(t, err)
+	require.Equal(t, time.Second, timeout)
+
+	_, err = set.GetString("Count")
+	require.Equal(t, "Option 'Count' is not a string", err.Error())
+	_, err = set.GetInt("timeout")
+	require.Equal(t, "Option 'timeout' is not an int", err.Error())
+	_, err = set.GetDuration("c")
+	require.Equal(t, "Option 'c' is not a duration", err.Error())
+	_, err = set.GetString("nope")
+	require.Equal(t, "Unknown option 'nope'", err.Error())
+	_, err = set.GetInt("nope")
+	require.NotNil(t, err)
+	_, err = set.GetDuration("nope")
+	require.NotNil(t, err)
+}
+
+func TestOptionSetSet(t *testing.T) {
+	set, opts := accessTestNewSet(t)
+
+	require.Nil(t, set.Set("Name", "bar"))
+	require.Equal(t, "bar", opts.Name)
+	require.Equal(t, SourceSet, set.Options["Name"].Source)
+
+	require.Nil(t, set.Set("--timeout", "5s"))
+	require.Equal(t, 5*time.Second, opts.Timeout)
+
+	require.Nil(t, set.Set("--no-color", "true"))
+	require.False(t, opts.Color)
+
+	require.Nil(t, set.Set("db-host", "db"))
+	require.Equal(t, "db", opts.Database.Host)
+
+	require.Nil(t, set.Set("Files", "a.txt"))
+	require.Nil(t, set.Set("Files", "b.txt"))
+	require.Equal(t, []string{"a.txt", "b.txt"}, opts.Files)
+}
+
+func TestOptionSetSet_Invalid(t *testing.T) {
+	set, opts := accessTestNewSet(t)
+
+	err := set.Set("c", "many")
+	require.Equal(t, "Invalid value 'many' for -c: parse error", err.Error())
+	require.Equal(t, int64(2), opts.Count)
+
+	err = set.Set("level", "trace")
+	require.Equal(t, "Invalid value 'trace' for --level, expected one of: debug, info", err.Error())
+	require.Equal(t, "info", opts.Level)
+	require.Equal(t, SourceDefault, set.Options["Level"].Source)
+
+	err = set.Set("pin", "12a4")
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "12a4")
+
+	require.Equal(t, "Unknown option 'nope'", set.Set("nope", "x").Error())
+}
+
+func TestOptionSetSet_Snapshot(t *testing.T) {
+	opts := TestAccessStruct{}
+	set, err := NewOptionSet(&opts, WithSnapshots())
+	require.Nil(t, err)
+	require.Nil(t, set.Set("name", "bar"))
+	require.Equal(t, "bar", set.Snapshot().(*TestAccessStruct).Name)
+}
+
+func TestOptionSetSet_SecretDash(t *testing.T) {
+	opts := TestSecretStruct{}
+	stdin := strings.NewReader("hunter2\n")
+	set, err := NewOptionSet(&opts, WithPromptIO(stdin, &bytes.Buffer{}))
+	require.Nil(t, err)
+	require.Nil(t, set.Set("password", "-"))
+	require.Equal(t, "-", opts.Password)
+	require.Equal(t, 8, stdin.Len())
+}
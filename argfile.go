@@ -0,0 +1,220 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Expands the given args using the arg files they reference. Every arg
+// starting with "@" is replaced by the args listed in the file at the path
+// following it. Args after "--" are not expanded.
+func (this *OptionSet) expandArgFiles(args []string) ([]string, error) {
+	expander := argFileExpander{fsys: this.fsys}
+	return expander.expand(args, nil, "")
+}
+
+// Expands arg files, keeping track of the files being read to detect loops
+type argFileExpander struct {
+	// the file system the files are read from
+	fsys fs.FS
+
+	// the absolute paths of the files being read, outermost first
+	files []string
+
+	// true once "--" is found, after which no args are expanded
+	done bool
+}
+
+// Expands the given args, read from the given file at the given lines. The
+// file is empty for the args given to Parse. Paths in arg files are relative
+// to the directory of the file.
+func (this *argFileExpander) expand(args []string, lines []int, file string) ([]string, error) {
+	expanded := []string{}
+
+	for n, arg := range args {
+		if this.done || len(arg) < 2 || arg[0] != '@' {
+			this.done = this.done || arg == "--"
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		path := arg[1:]
+
+		if file != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+
+		included, err := this.read(path)
+
+		if _, ok := err.(*ArgFileError); err != nil && !ok {
+			if file == "" {
+				return nil, &ArgFileError{File: path, Err: err}
+			}
+
+			return nil, &ArgFileError{File: file, Line: lines[n], Err: err}
+		} else if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, included...)
+	}
+
+	return expanded, nil
+}
+
+// Reads and expands the args in the arg file at the given path
+func (this *argFileExpander) read(path string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range this.files {
+		if file == abs {
+			return nil, errors.New(fmt.Sprintf(
+				"Arg file %s is included recursively",
+				path))
+		}
+	}
+
+	data, err := fs.ReadFile(this.fsys, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	args, lines, err := splitArgFile(string(data))
+
+	if err != nil {
+		err.(*ArgFileError).File = path
+		return nil, err
+	}
+
+	this.files = append(this.files, abs)
+	defer func() { this.files = this.files[:len(this.files)-1] }()
+
+	return this.expand(args, lines, path)
+}
+
+// Splits the contents of an arg file into args, following the quoting rules
+// of the shell: args are separated by whitespace, single quotes keep their
+// contents as is, double quotes and backslashes escape special characters and
+// "#" starts a comment until the end of the line. Returns the args and the
+// line each arg starts on.
+func splitArgFile(data string) ([]string, []int, error) {
+	args := []string{}
+	lines := []int{}
+	arg := strings.Builder{}
+	inArg := false
+	line := 1
+
+	start := func() {
+		if !inArg {
+			inArg = true
+			lines = append(lines, line)
+		}
+	}
+
+	end := func() {
+		if inArg {
+			args = append(args, arg.String())
+			arg.Reset()
+			inArg = false
+		}
+	}
+
+	for pos := 0; pos < len(data); pos++ {
+		char := data[pos]
+
+		switch {
+		case char == '\n':
+			end()
+			line++
+
+		case char == ' ' || char == '\t' || char == '\r':
+			end()
+
+		case char == '#' && !inArg:
+			for pos+1 < len(data) && data[pos+1] != '\n' {
+				pos++
+			}
+
+		case char == '\\':
+			if pos+1 >= len(data) {
+				return nil, nil, &ArgFileError{Line: line, Err: errors.New("Missing character after '\\'")}
+			}
+
+			pos++
+
+			// a backslash before a line break continues the line
+			if data[pos] == '\n' {
+				line++
+				continue
+			}
+
+			start()
+			arg.WriteByte(data[pos])
+
+		case char == '\'':
+			start()
+			quoteLine := line
+			closing := strings.IndexByte(data[pos+1:], '\'')
+
+			if closing < 0 {
+				return nil, nil, &ArgFileError{Line: quoteLine, Err: errors.New("Missing closing \"'\"")}
+			}
+
+			quoted := data[pos+1 : pos+1+closing]
+			arg.WriteString(quoted)
+			line += strings.Count(quoted, "\n")
+			pos += closing + 1
+
+		case char == '"':
+			start()
+			quoteLine := line
+			closed := false
+
+			for pos++; pos < len(data); pos++ {
+				char = data[pos]
+
+				if char == '"' {
+					closed = true
+					break
+				}
+
+				if char == '\n' {
+					line++
+				}
+
+				// only the characters special in double quotes are escaped
+				if char == '\\' && pos+1 < len(data) && strings.IndexByte("\"\\$`\n", data[pos+1]) >= 0 {
+					pos++
+
+					if data[pos] == '\n' {
+						line++
+						continue
+					}
+
+					char = data[pos]
+				}
+
+				arg.WriteByte(char)
+			}
+
+			if !closed {
+				return nil, nil, &ArgFileError{Line: quoteLine, Err: errors.New("Missing closing '\"'")}
+			}
+
+		default:
+			start()
+			arg.WriteByte(char)
+		}
+	}
+
+	end()
+	return args, lines, nil
+}
@@ -0,0 +1,127 @@
+package opts
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type TestArgFileStruct struct {
+	Name    string   `long:"name" short:"n"`
+	Verbose int      `short:"v" count:"true"`
+	Files   []string `positional:"true"`
+}
+
+func argFileTestWrite(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestSplitArgFile(t *testing.T) {
+	args, lines, err := splitArgFile("--name 'foo bar'\n# a comment\n  -v \"a \\\"b\\\" \\c\" # trailing\nx\\ y it''s \\\nz\n")
+	require.Nil(t, err)
+	require.Equal(t, []string{"--name", "foo bar", "-v", "a \"b\" \\c", "x y", "its", "z"}, args)
+	require.Equal(t, []int{1, 1, 3, 3, 4, 4, 5}, lines)
+}
+
+func TestSplitArgFile_Empty(t *testing.T) {
+	args, lines, err := splitArgFile("\n  # nothing here\n\n")
+	require.Nil(t, err)
+	require.Equal(t, []string{}, args)
+	require.Equal(t, []int{}, lines)
+}
+
+func TestSplitArgFile_MultilineQuotes(t *testing.T) {
+	args, lines, err := splitArgFile("'a\nb' \"c\nd\"\ne")
+	require.Nil(t, err)
+	require.Equal(t, []string{"a\nb", "c\nd", "e"}, args)
+	require.Equal(t, []int{1, 2, 4}, lines)
+}
+
+func TestSplitArgFile_Unterminated(t *testing.T) {
+	_, _, err := splitArgFile("-v\n'foo\n")
+	require.Equal(t, &ArgFileError{Line: 2, Err: err.(*ArgFileError).Err}, err)
+	require.Equal(t, "Missing closing \"'\"", err.(*ArgFileError).Err.Error())
+
+	_, _, err = splitArgFile("-v\n\n\"foo")
+	require.Equal(t, 3, err.(*ArgFileError).Line)
+
+	_, _, err = splitArgFile("foo\\")
+	require.Equal(t, 1, err.(*ArgFileError).Line)
+}
+
+func TestOptionSetParse_ArgFiles(t *testing.T) {
+	dir := t.TempDir()
+	argFileTestWrite(t, dir, "common.args", "-vv\n")
+	path := argFileTestWrite(t, dir, "build.args", "--name 'foo bar'\n@common.args\n")
+
+	opts := TestArgFileStruct{}
+	set, err := NewOptionSet(&opts, WithArgFiles())
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"-v", "@" + path, "--", "@" + path}))
+	require.Equal(t, "foo bar", opts.Name)
+	require.Equal(t, 3, opts.Verbose)
+	require.Equal(t, []string{"@" + path}, opts.Files)
+}
+
+func TestOptionSetParse_ArgFilesPositionals(t *testing.T) {
+	dir := t.TempDir()
+	path := argFileTestWrite(t, dir, "files.args", "# sources\nmain.go\n'util file.go'\n")
+
+	opts := TestArgFileStruct{}
+	set, err := NewOptionSet(&opts, WithArgFiles())
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"-n", "foo", "@" + path, "test.go"}))
+	require.Equal(t, "foo", opts.Name)
+	require.Equal(t, []string{"main.go", "util file.go", "test.go"}, opts.Files)
+}
+
+func TestOptionSetParse_ArgFilesDisabled(t *testing.T) {
+	opts := TestArgFileStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"@missing.args"}))
+	require.Equal(t, []string{"@missing.args"}, opts.Files)
+}
+
+func TestOptionSetParse_ArgFilesLoop(t *testing.T) {
+	dir := t.TempDir()
+	first := argFileTestWrite(t, dir, "first.args", "-v\n@second.args\n")
+	second := argFileTestWrite(t, dir, "second.args", "-v\n\n@first.args\n")
+
+	set, err := NewOptionSet(&TestArgFileStruct{}, WithArgFiles())
+	require.Nil(t, err)
+	err = set.Parse([]string{"@" + first})
+	require.IsType(t, &ArgFileError{}, err)
+	require.Equal(t, second, err.(*ArgFileError).File)
+	require.Equal(t, 3, err.(*ArgFileError).Line)
+	require.Contains(t, err.Error(), "is included recursively")
+}
+
+func TestOptionSetParse_ArgFilesMissing(t *testing.T) {
+	dir := t.TempDir()
+	path := argFileTestWrite(t, dir, "build.args", "-v\n@missing.args\n")
+
+	set, err := NewOptionSet(&TestArgFileStruct{}, WithArgFiles())
+	require.Nil(t, err)
+	err = set.Parse([]string{"@" + path})
+	require.IsType(t, &ArgFileError{}, err)
+	require.Equal(t, path, err.(*ArgFileError).File)
+	require.Equal(t, 2, err.(*ArgFileError).Line)
+
+	err = set.Parse([]string{"@" + filepath.Join(dir, "missing.args")})
+	require.IsType(t, &ArgFileError{}, err)
+	require.Equal(t, 0, err.(*ArgFileError).Line)
+}
+
+func TestOptionSetParse_ArgFilesSyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	path := argFileTestWrite(t, dir, "build.args", "-v\n--name \"foo\n")
+
+	set, err := NewOptionSet(&TestArgFileStruct{}, WithArgFiles())
+	require.Nil(t, err)
+	err = set.Parse([]string{"@" + path})
+	require.Equal(t, "Invalid arg file "+path+" at line 2: Missing closing '\"'", err.Error())
+}
@@ -0,0 +1,208 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Adds an option storing its value in the given target, which must be a
+// pointer to a supported type. The option behaves the same as one created by
+// NewOption from struct tags. The Name defaults to the long or short name and
+// the Default to the current value of the target. Positional options are
+// defined by setting the Position, or the "positional" tag to "true" for
+// options storing the leftover args.
+func (this *OptionSet) AddOption(opt Option, target interface{}) (*Option, error) {
+	value := reflect.ValueOf(target)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil, errors.New("Target is not a pointer.")
+	}
+
+	tags := copyTags(opt.Tags)
+
+	if opt.Position > 0 && !tags.Has("positional") {
+		tags["positional"] = strconv.Itoa(opt.Position)
+	}
+
+	if opt.Default != "" && !tags.Has("default") {
+		tags["default"] = opt.Default
+	}
+
+	opt.Kind = value.Elem().Kind()
+	opt.Source = SourceDefault
+	opt.Tags = tags
+	opt.Type = value.Elem().Type().String()
+	opt.pointer = target
+
+	if opt.Env != "" {
+		if val, ok := this.lookupEnv(opt.Env); ok {
+			opt.Default = val
+			opt.Source = SourceEnv
+		}
+	}
+
+	if opt.Name == "" && opt.Long != "" {
+		opt.Name = opt.Long
+	} else if opt.Name == "" {
+		opt.Name = opt.Short
+	}
+
+	if opt.Name == "" {
+		return nil, errors.New("Option has no name, long or short name.")
+	}
+
+	if _, ok := this.Options[opt.Name]; ok {
+		return nil, &DefinitionError{Problems: []string{
+			fmt.Sprintf("Option '%s' is already defined", opt.Name),
+		}}
+	}
+
+	if opt.ArgName == "" {
+		opt.ArgName = strings.ToUpper(opt.Name)
+	}
+
+	if opt.Default == "" {
+		opt.Default = formatDefault(opt.Type, target)
+	}
+
+	err := opt.check(reflect.TypeOf(target).Elem())
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.prepare(&opt)
+	err = validateDefinitions(append(this.List(), &opt))
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = this.register(&opt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	this.publish()
+	return &opt, nil
+}
+
+// Defines a bool option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Bool(long, short string, def bool, description string) (*bool, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines a time.Duration option with the given names, default and
+// description. Returns the pointer the value is stored in.
+func (this *OptionSet) Duration(long, short string, def time.Duration, description string) (*time.Duration, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines a float64 option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Float64(long, short string, def float64, description string) (*float64, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines an int option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Int(long, short string, def int, description string) (*int, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines an int64 option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Int64(long, short string, def int64, description string) (*int64, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines a string option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) String(long, short, def, description string) (*string, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines a uint option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Uint(long, short string, def uint, description string) (*uint, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Defines a uint64 option with the given names, default and description.
+// Returns the pointer the value is stored in.
+func (this *OptionSet) Uint64(long, short string, def uint64, description string) (*uint64, error) {
+	value := def
+	err := this.addTyped(long, short, description, &value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
+// Adds an option with the given names and description for the typed helpers
+func (this *OptionSet) addTyped(long, short, description string, target interface{}) error {
+	_, err := this.AddOption(Option{
+		Description: description,
+		Long:        long,
+		Short:       short,
+	}, target)
+
+	return err
+}
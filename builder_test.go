@@ -0,0 +1,214 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddOption(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	var name string
+	opt, err := set.AddOption(Option{
+		Default:     "foo",
+		Description: "The name to use",
+		Long:        "name",
+		Short:       "n",
+	}, &name)
+	require.Nil(t, err)
+	require.Equal(t, "name", opt.Name)
+	require.Equal(t, "string", opt.Type)
+	require.Equal(t, "foo", name)
+	require.Equal(t, opt, set.Options["name"])
+	require.Nil(t, set.Parse([]string{"-n", "bar"}))
+	require.Equal(t, "bar", name)
+}
+
+type TestAddOptionSnapshotStruct struct {
+	Name string `long:"name"`
+
+	Extra string
+}
+
+func TestAddOption_Snapshot(t *testing.T) {
+	opts := TestAddOptionSnapshotStruct{}
+	set, err := NewOptionSet(&opts, WithSnapshots())
+	require.Nil(t, err)
+	_, err = set.AddOption(Option{Default: "foo", Long: "extra"}, &opts.Extra)
+	require.Nil(t, err)
+	require.Equal(t, "foo", set.Snapshot().(*TestAddOptionSnapshotStruct).Extra)
+}
+
+func TestAddOption_CurrentValueDefault(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	retries := 3
+	opt, err := set.AddOption(Option{Short: "r"}, &retries)
+	require.Nil(t, err)
+	require.Equal(t, "r", opt.Name)
+	require.Equal(t, "3", opt.Default)
+}
+
+func TestAddOption_Positional(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	var source string
+	var files []string
+	_, err := set.AddOption(Option{Name: "Source", Position: 1, Required: true}, &source)
+	require.Nil(t, err)
+	_, err = set.AddOption(Option{
+		Name: "Files",
+		Tags: TagSet{"positional": "true"},
+	}, &files)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"src", "a", "b"}))
+	require.Equal(t, "src", source)
+	require.Equal(t, []string{"a", "b"}, files)
+	require.Equal(t, "SOURCE [FILES...]", set.Usage())
+}
+
+func TestAddOption_PositionalDefault(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	port := 0
+	_, err := set.AddOption(Option{Default: "80", Name: "Port", Position: 1}, &port)
+	require.Nil(t, err)
+	require.Equal(t, 80, port)
+	require.Nil(t, set.Parse([]string{"8080"}))
+	require.Equal(t, 8080, port)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, 80, port)
+}
+
+func TestAddOption_NotPointer(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	_, err := set.AddOption(Option{Long: "name"}, "foo")
+	require.NotNil(t, err)
+	_, err = set.AddOption(Option{Long: "name"}, (*string)(nil))
+	require.NotNil(t, err)
+}
+
+func TestAddOption_NoName(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	var name string
+	_, err := set.AddOption(Option{}, &name)
+	require.NotNil(t, err)
+}
+
+func TestAddOption_Duplicate(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	var name, other string
+	_, err := set.AddOption(Option{Long: "name"}, &name)
+	require.Nil(t, err)
+	_, err = set.AddOption(Option{Long: "name"}, &other)
+	require.IsType(t, &DefinitionError{}, err)
+	_, err = set.AddOption(Option{Name: "Other", Short: "x", Aliases: []string{"name"}}, &other)
+	require.IsType(t, &DefinitionError{}, err)
+	require.Equal(t,
+		[]string{"Flag 'name' is defined by options 'name' and 'Other'"},
+		err.(*DefinitionError).Problems)
+}
+
+func TestAddOption_InvalidType(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	var count string
+	_, err := set.AddOption(Option{Long: "count", Counter: true}, &count)
+	require.NotNil(t, err)
+}
+
+func TestAddOption_Settings(t *testing.T) {
+	set := NewEmptyOptionSet("test", WithNaming(KebabCase), WithNegatableBools())
+	color := true
+	opt, err := set.AddOption(Option{Name: "UseColor"}, &color)
+	require.Nil(t, err)
+	require.Equal(t, "use-color", opt.Long)
+	require.Nil(t, set.Parse([]string{"--no-use-color"}))
+	require.False(t, color)
+}
+
+func TestAddOption_MixedWithStruct(t *testing.T) {
+	opts := TestOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	level, err := set.Int("level", "l", 1, "The level.")
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"-l", "5", "-n", "bar"}))
+	require.Equal(t, 5, *level)
+	require.Equal(t, "bar", opts.Name)
+
+	_, err = set.Int("verbose", "", 1, "Conflicts.")
+	require.IsType(t, &DefinitionError{}, err)
+}
+
+func TestOptionSetTypedHelpers(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	b, err := set.Bool("bool", "b", false, "A bool.")
+	require.Nil(t, err)
+	d, err := set.Duration("duration", "d", time.Second, "A duration.")
+	require.Nil(t, err)
+	f, err := set.Float64("float64", "f", 1.5, "A float64.")
+	require.Nil(t, err)
+	i, err := set.Int("int", "i", 1, "An int.")
+	require.Nil(t, err)
+	i64, err := set.Int64("int64", "I", 2, "An int64.")
+	require.Nil(t, err)
+	s, err := set.String("string", "s", "foo", "A string.")
+	require.Nil(t, err)
+	u, err := set.Uint("uint", "u", 3, "A uint.")
+	require.Nil(t, err)
+	u64, err := set.Uint64("uint64", "U", 4, "A uint64.")
+	require.Nil(t, err)
+
+	require.Equal(t, time.Second, *d)
+	require.Equal(t, "foo", *s)
+
+	err = set.Parse([]string{
+		"-b", "-d", "1m", "-f", "2.5", "-i", "10", "-I", "20", "-s", "bar",
+		"-u", "30", "-U", "40",
+	})
+	require.Nil(t, err)
+	require.True(t, *b)
+	require.Equal(t, time.Minute, *d)
+	require.Equal(t, 2.5, *f)
+	require.Equal(t, 10, *i)
+	require.Equal(t, int64(20), *i64)
+	require.Equal(t, "bar", *s)
+	require.Equal(t, uint(30), *u)
+	require.Equal(t, uint64(40), *u64)
+
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	require.Contains(t, buf.String(), "  -d, --duration duration\n    \tA duration. (default 1s)\n")
+}
+
+func TestOptionSetTypedHelpers_Conflict(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	_, err := set.String("name", "n", "", "")
+	require.Nil(t, err)
+	value, err := set.Bool("other", "n", false, "")
+	require.Nil(t, value)
+	require.IsType(t, &DefinitionError{}, err)
+}
+
+func TestAddOption_Env(t *testing.T) {
+	os.Setenv("TEST_ADD_OPTION_ENV", "7")
+	defer os.Unsetenv("TEST_ADD_OPTION_ENV")
+	set := NewEmptyOptionSet("test")
+	retries := 3
+	opt, err := set.AddOption(Option{Env: "TEST_ADD_OPTION_ENV", Long: "retries"}, &retries)
+	require.Nil(t, err)
+	require.Equal(t, 7, retries)
+	require.Equal(t, SourceEnv, opt.Source)
+	require.Equal(t, reflect.Int, opt.Kind)
+}
+
+func TestAddOption_Order(t *testing.T) {
+	set := NewEmptyOptionSet("test")
+	_, err := set.String("zulu", "", "", "")
+	require.Nil(t, err)
+	_, err = set.String("alpha", "", "", "")
+	require.Nil(t, err)
+	list := set.List()
+	require.Equal(t, "zulu", list[0].Name)
+	require.Equal(t, "alpha", list[1].Name)
+}
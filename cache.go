@@ -0,0 +1,198 @@
+package opts
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// The options read from struct types, keyed by typeKey. Structs are only
+// walked and their tags only parsed the first time a set is created for them.
+var typeCache sync.Map
+
+// The compiled patterns of options, keyed by pattern
+var patternCache sync.Map
+
+// The key of the cached options of a struct type
+type typeKey struct {
+	// the struct type
+	typ reflect.Type
+
+	// the tag namespace the options were read with
+	namespace string
+}
+
+// The cached options of a struct type
+type cachedType struct {
+	// the options of the fields, in declaration order
+	fields []*cachedField
+
+	// the error reading the field following the last one, if any
+	err error
+}
+
+// The cached option of a struct field
+type cachedField struct {
+	// the index sequence of the field, see reflect.Value.FieldByIndex
+	index []int
+
+	// the name of the field (i.e. "Host")
+	name string
+
+	// the path of the struct the field is in (i.e. "Database")
+	path string
+
+	// the group of the field (i.e. "database")
+	group string
+
+	// the option of the field, not bound to any field value. Copied by every
+	// option bound from it.
+	prototype *Option
+
+	// the error creating the prototype, reported when binding
+	err error
+}
+
+// Returns the cached options of the given struct type, read with the given
+// tag namespace. The options are read and cached if needed.
+func cachedFields(dataType reflect.Type, namespace string) *cachedType {
+	key := typeKey{typ: dataType, namespace: namespace}
+
+	if cached, ok := typeCache.Load(key); ok {
+		return cached.(*cachedType)
+	}
+
+	fields, err := readFields(dataType, namespace, nil, "", "")
+	cached, _ := typeCache.LoadOrStore(key, &cachedType{fields: fields, err: err})
+	return cached.(*cachedType)
+}
+
+// Reads the options of the fields of the given struct type, in declaration
+// order. Struct fields tagged with a group are read recursively, their
+// options are named by the path of the field (i.e. "Database.Host"). Reading
+// stops at the first field with an invalid definition.
+func readFields(dataType reflect.Type, namespace string, index []int, path, group string) ([]*cachedField, error) {
+	fields := []*cachedField{}
+
+	for n := 0; n < dataType.NumField(); n++ {
+		fieldType := dataType.Field(n)
+
+		// ignore field without tags
+		if fieldType.Tag == "" {
+			continue
+		}
+
+		tags, err := fieldTags(fieldType, namespace)
+
+		if err != nil {
+			return fields, err
+		}
+
+		// ignore fields without tags for this package
+		if tags == nil {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), n)
+
+		if fieldType.Type.Kind() == reflect.Struct && tags["group"] != "" {
+			nested, err := readFields(
+				fieldType.Type,
+				namespace,
+				fieldIndex,
+				joinPath(path, fieldType.Name),
+				joinPath(group, tags["group"]))
+
+			fields = append(fields, nested...)
+
+			if err != nil || (len(nested) > 0 && nested[len(nested)-1].err != nil) {
+				return fields, err
+			}
+
+			continue
+		}
+
+		prototype, err := newPrototype(fieldType.Name, fieldType.Type, tags)
+
+		fields = append(fields, &cachedField{
+			index:     fieldIndex,
+			name:      fieldType.Name,
+			path:      path,
+			group:     group,
+			prototype: prototype,
+			err:       err,
+		})
+
+		if err != nil {
+			return fields, nil
+		}
+	}
+
+	return fields, nil
+}
+
+// Creates the option of this field of the given struct value, reading the
+// default from the environment using the given function
+func (this *cachedField) bind(dataValue reflect.Value, lookupEnv func(string) (string, bool)) (*Option, error) {
+	fieldValue := dataValue.FieldByIndex(this.index)
+
+	if !fieldValue.CanAddr() {
+		return nil, errors.New("Cannot address field value: " + this.name)
+	}
+
+	ptrIface := fieldValue.Addr()
+
+	if !ptrIface.CanInterface() {
+		return nil, errors.New("Cannot interface field address: " + this.name)
+	}
+
+	if this.err != nil {
+		return nil, this.err
+	}
+
+	// copy the slices and tags, so changing them does not change the other
+	// options bound from the prototype
+	opt := *this.prototype
+	opt.Aliases = append([]string{}, opt.Aliases...)
+	opt.Choices = append([]string{}, opt.Choices...)
+	opt.DeprecatedAliases = append([]string{}, opt.DeprecatedAliases...)
+	opt.Tags = copyTags(opt.Tags)
+	opt.index = append([]int{}, this.index...)
+	opt.bind(ptrIface.Interface(), lookupEnv)
+	return &opt, nil
+}
+
+// Parses the tags of the given field. If a tag namespace is given the tags are
+// read from the namespaced tag, otherwise the field tags are used. Returns nil
+// if the field has no tags for this package, like fields only tagged for json.
+func fieldTags(fieldType reflect.StructField, namespace string) (TagSet, error) {
+	tags, err := ParseTagSet(string(fieldType.Tag))
+
+	if err != nil {
+		err.(*TagSyntaxError).Field = fieldType.Name
+		return nil, err
+	}
+
+	if namespace == "" && len(tags.UnknownKeys()) == len(tags) {
+		return nil, nil
+	}
+
+	if namespace == "" {
+		return tags, nil
+	}
+
+	raw, ok := tags[namespace]
+
+	if !ok || raw == "-" {
+		return nil, nil
+	}
+
+	tags, err = ParseNamespacedTag(raw)
+
+	if err != nil {
+		err.(*TagSyntaxError).Field = fieldType.Name
+		return nil, err
+	}
+
+	return tags, nil
+}
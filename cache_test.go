@@ -0,0 +1,144 @@
+package opts
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type TestCacheStruct struct {
+	Name     string                  `long:"name" alias:"label" default:"foo" env:"NAME" opts:"long=title"`
+	Count    int                     `long:"count" short:"c"`
+	Files    []string                `positional:"true"`
+	Database TestCacheDatabaseStruct `group:"database" opts:"group=db"`
+}
+
+type TestCacheDatabaseStruct struct {
+	Host string `long:"db-host" opts:"long=host"`
+}
+
+type TestCacheInvalidStruct struct {
+	Name  string `long:"name"`
+	Count int    `long:"count" positional:"first"`
+	Other string `long:"other"`
+}
+
+type TestCacheUnexportedStruct struct {
+	Name  string `long:"name"`
+	count int    `long:"count" positional:"first"`
+}
+
+func TestCachedFields(t *testing.T) {
+	typ := reflect.TypeOf(TestCacheStruct{})
+	cached := cachedFields(typ, "")
+	require.True(t, cached == cachedFields(typ, ""))
+	require.Nil(t, cached.err)
+	require.Len(t, cached.fields, 4)
+	require.Equal(t, []int{3, 0}, cached.fields[3].index)
+	require.Equal(t, "Database", cached.fields[3].path)
+	require.Equal(t, "database", cached.fields[3].group)
+
+	namespaced := cachedFields(typ, "opts")
+	require.False(t, cached == namespaced)
+	require.Len(t, namespaced.fields, 2)
+	require.Equal(t, "title", namespaced.fields[0].prototype.Long)
+	require.Equal(t, "db", namespaced.fields[1].group)
+}
+
+func TestNewOptionSet_Cached(t *testing.T) {
+	first := TestCacheStruct{}
+	set, err := NewOptionSet(&first, WithEnv(func(name string) (string, bool) { return "bar", name == "NAME" }))
+	require.Nil(t, err)
+
+	second := TestCacheStruct{Count: 2}
+	other, err := NewOptionSet(&second)
+	require.Nil(t, err)
+
+	require.Nil(t, set.Parse([]string{"--db-host", "db", "a"}))
+	require.Nil(t, other.Parse([]string{"-c", "3"}))
+	require.Equal(t, TestCacheStruct{Name: "bar", Files: []string{"a"}, Database: TestCacheDatabaseStruct{Host: "db"}}, first)
+	require.Equal(t, TestCacheStruct{Name: "foo", Count: 3, Files: []string{}}, second)
+
+	require.Equal(t, "bar", set.Lookup("name").Default)
+	require.Equal(t, SourceEnv, set.Lookup("name").Source)
+	require.Equal(t, "foo", other.Lookup("name").Default)
+	require.Equal(t, "2", other.Lookup("count").Default)
+	require.False(t, set.Lookup("name") == other.Lookup("name"))
+	require.Equal(t, "Database.Host", other.Lookup("db-host").Name)
+}
+
+func TestNewOptionSet_CachedCopies(t *testing.T) {
+	set, err := NewOptionSet(&TestCacheStruct{})
+	require.Nil(t, err)
+	opt := set.Lookup("name")
+	opt.Tags["long"] = "changed"
+	opt.Aliases[0] = "changed"
+
+	other, err := NewOptionSet(&TestCacheStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "name", other.Lookup("name").Tags["long"])
+	require.Equal(t, []string{"label"}, other.Lookup("name").Aliases)
+}
+
+func TestNewOptionSet_CachedErrors(t *testing.T) {
+	for n := 0; n < 2; n++ {
+		_, err := NewOptionSet(&TestCacheInvalidStruct{})
+		require.Equal(t, "Invalid positional index for field Count: first", err.Error())
+
+		_, err = NewOptionSet(&TestCacheUnexportedStruct{})
+		require.Equal(t, "Cannot interface field address: count", err.Error())
+
+		_, err = NewOptionSet(&TestInvalidTagOptionSetStruct{})
+		require.IsType(t, &TagSyntaxError{}, err)
+		require.Equal(t, "Name", err.(*TagSyntaxError).Field)
+	}
+}
+
+func TestNewOptionSet_CachedConcurrent(t *testing.T) {
+	wait := sync.WaitGroup{}
+
+	for n := 0; n < 8; n++ {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+			opts := TestCacheStruct{}
+			set, err := NewOptionSet(&opts, WithTagNamespace("opts"))
+
+			if assert.Nil(t, err) {
+				assert.Nil(t, set.Parse([]string{"--title", "bar"}))
+				assert.Equal(t, "bar", opts.Name)
+			}
+		}()
+	}
+
+	wait.Wait()
+}
+
+func BenchmarkNewOptionSet(b *testing.B) {
+	key := typeKey{typ: reflect.TypeOf(TestCacheStruct{})}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			if _, err := NewOptionSet(&TestCacheStruct{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			typeCache.Delete(key)
+
+			if _, err := NewOptionSet(&TestCacheStruct{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generates the Go source of the options struct described by the given spec,
+// read from the spec file with the given name
+func Generate(spec *Spec, source string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "// Code generated by optsgen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&buf, "package %s\n\n", spec.Package)
+
+	imports := map[string]bool{}
+
+	for _, path := range spec.Imports {
+		imports[path] = true
+	}
+
+	if usesTime(spec.Options) {
+		imports["time"] = true
+	}
+
+	if len(imports) > 0 {
+		paths := []string{}
+
+		for path := range imports {
+			paths = append(paths, strconv.Quote(path))
+		}
+
+		sort.Strings(paths)
+		fmt.Fprintf(&buf, "import (\n%s\n)\n\n", strings.Join(paths, "\n"))
+	}
+
+	writeStruct(&buf, spec.Type, spec.Options)
+	return format.Source(buf.Bytes())
+}
+
+// Writes the struct type with the given name and options, followed by the
+// struct types of its groups
+func writeStruct(buf *bytes.Buffer, name string, options []*OptionSpec) {
+	fmt.Fprintf(buf, "type %s struct {\n", name)
+
+	for _, opt := range options {
+		fmt.Fprintf(buf, "%s %s %s\n", opt.Field, opt.Type, structTag(opt))
+	}
+
+	buf.WriteString("}\n")
+
+	for _, opt := range options {
+		if opt.Group != "" {
+			buf.WriteString("\n")
+			writeStruct(buf, opt.Type, opt.Options)
+		}
+	}
+}
+
+// Returns the struct tag of the given option as a Go string literal
+func structTag(opt *OptionSpec) string {
+	pairs := []string{}
+
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+":"+strconv.Quote(value))
+		}
+	}
+
+	flag := func(key string, value bool) {
+		if value {
+			add(key, "true")
+		}
+	}
+
+	add("long", opt.Long)
+	add("short", opt.Short)
+	add("alias", strings.Join(opt.Aliases, ","))
+	add("name", opt.ArgName)
+	add("positional", opt.Positional)
+	flag("required", opt.Required)
+	add("default", opt.Default)
+	add("env", opt.Env)
+	add("choices", strings.Join(opt.Choices, ","))
+	add("min", opt.Min)
+	add("max", opt.Max)
+	add("pattern", opt.Pattern)
+	flag("count", opt.Count)
+	flag("negatable", opt.Negatable)
+	flag("hidden", opt.Hidden)
+	flag("secret", opt.Secret)
+
+	if opt.Reloadable != nil {
+		add("reloadable", strconv.FormatBool(*opt.Reloadable))
+	}
+
+	add("deprecated", opt.Deprecated)
+	add("deprecated-aliases", strings.Join(opt.DeprecatedAliases, ","))
+	add("description", opt.Description)
+	add("help", opt.Help)
+	add("group", opt.Group)
+
+	tag := strings.Join(pairs, " ")
+
+	// raw strings cannot hold backquotes, which descriptions may use to name
+	// the value of a flag
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+
+	return "`" + tag + "`"
+}
+
+// Returns true if any of the given options, or the options of their groups,
+// is of a type from the time package
+func usesTime(options []*OptionSpec) bool {
+	for _, opt := range options {
+		if strings.Contains(opt.Type, "time.") || usesTime(opt.Options) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/ronelliott/go-opts"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	output, err := Generate(specTestRead(t), "testdata/cli.yaml")
+	require.Nil(t, err)
+
+	golden, err := ioutil.ReadFile("options_gen_test.go")
+	require.Nil(t, err)
+	require.Equal(t, string(golden), string(output))
+}
+
+func TestGenerate_OptionSet(t *testing.T) {
+	// options_gen_test.go is the output for testdata/cli.yaml
+	options := CLIOptions{}
+	set, err := opts.NewOptionSet(&options, opts.WithEnv(func(string) (string, bool) { return "", false }))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--db-host", "db", "-vv", "src", "10.0.0.1", "a", "b"}))
+	require.Equal(t, "foo", options.Name)
+	require.Equal(t, 2, options.Verbose)
+	require.Equal(t, "db", options.Database.Host)
+	require.Equal(t, "src", options.Source)
+	require.Equal(t, "10.0.0.1", options.Addr.String())
+	require.Equal(t, []string{"a", "b"}, options.Files)
+}
+
+func TestGenerate_NoImports(t *testing.T) {
+	output, err := Generate(&Spec{
+		Package: "app",
+		Type:    "Options",
+		Options: []*OptionSpec{{Field: "Debug", Type: "bool", Long: "debug"}},
+	}, "cli.json")
+	require.Nil(t, err)
+	require.Equal(t, "// Code generated by optsgen from cli.json. DO NOT EDIT.\n\n"+
+		"package app\n\n"+
+		"type Options struct {\n"+
+		"\tDebug bool `long:\"debug\"`\n"+
+		"}\n",
+		string(output))
+}
+
+func TestStructTag(t *testing.T) {
+	reloadable := true
+	require.Equal(t, "`long:\"name\" reloadable:\"true\"`", structTag(&OptionSpec{Long: "name", Reloadable: &reloadable}))
+	require.Equal(t, "``", structTag(&OptionSpec{}))
+	require.Equal(t, "\"description:\\\"The `name`.\\\"\"", structTag(&OptionSpec{Description: "The `name`."}))
+}
@@ -0,0 +1,160 @@
+// Command optsgen generates Go options structs from spec files shared with
+// tools not written in Go, and writes the spec of existing options structs.
+//
+// Generate a struct, i.e. from a go:generate comment:
+//
+//	//go:generate go run github.com/ronelliott/go-opts/cmd/optsgen --spec cli.yaml --out options_gen.go
+//
+// Write the spec of an existing struct:
+//
+//	optsgen --reverse --type Options --spec cli.yaml
+//
+// Generate a function creating the option set of an existing struct without
+// reflection, for programs where creating option sets shows up in profiles:
+//
+//	//go:generate go run github.com/ronelliott/go-opts/cmd/optsgen --parser --type Options --out options_parser.go
+//
+// With --check, the output file is compared to the generated output instead of
+// being written, failing if it is out of date. Use this to check specs and
+// structs are kept in sync in CI.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ronelliott/go-opts"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+type Options struct {
+	Check bool `long:"check" description:"Fail if the output is out of date, instead of writing it."`
+
+	Dir string `long:"dir" default:"." description:"The directory of the package with the struct, for --parser and --reverse."`
+
+	Help bool `long:"help" short:"h" description:"Show this help."`
+
+	Namespace string `long:"namespace" description:"The key of the tag holding the option tags, for --parser and --reverse."`
+
+	Out string `long:"out" short:"o" description:"The Go file to write, stdout if not given."`
+
+	Parser bool `long:"parser" short:"p" description:"Write a function creating the option set of an existing struct without reflection, instead of the struct."`
+
+	Reverse bool `long:"reverse" short:"r" description:"Write the spec of an existing struct, instead of the struct."`
+
+	Spec string `long:"spec" short:"s" description:"The spec file, YAML or JSON by extension."`
+
+	Type string `long:"type" short:"t" description:"The name of the struct type, for --parser and --reverse."`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Runs the command with the given args, returning the exit status
+func run(args []string, stdout, stderr io.Writer) int {
+	options := Options{}
+	set, err := opts.NewOptionSet(&options, opts.WithOutput(stdout, stderr))
+
+	if err == nil {
+		err = set.Parse(args)
+	}
+
+	if options.Help {
+		fmt.Fprintln(stdout, "Usage: optsgen --spec file [--out file] [--reverse --type name] [--parser --type name] [--check]")
+		set.PrintHelp()
+		return 0
+	}
+
+	if err == nil {
+		err = generate(&options, stdout)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "optsgen:", err)
+		return 1
+	}
+
+	return 0
+}
+
+// Generates the struct or spec, writing or checking the output
+func generate(options *Options, stdout io.Writer) error {
+	var output []byte
+	var err error
+	target := options.Out
+
+	if options.Spec == "" && !options.Parser {
+		return errors.New("Missing required option: --spec")
+	}
+
+	if options.Parser {
+		if options.Type == "" {
+			return errors.New("--type is required with --parser")
+		}
+
+		output, err = GenerateParser(options.Dir, options.Type, options.Namespace)
+
+		if err != nil {
+			return err
+		}
+	} else if options.Reverse {
+		if options.Type == "" {
+			return errors.New("--type is required with --reverse")
+		}
+
+		target = options.Spec
+		spec, err := Reverse(options.Dir, options.Type, options.Namespace)
+
+		if err != nil {
+			return err
+		}
+
+		output, err = EncodeSpec(options.Spec, spec)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err := ioutil.ReadFile(options.Spec)
+
+		if err != nil {
+			return err
+		}
+
+		spec, err := DecodeSpec(options.Spec, data)
+
+		if err != nil {
+			return err
+		}
+
+		output, err = Generate(spec, options.Spec)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Check {
+		current, err := ioutil.ReadFile(target)
+
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(current, output) {
+			return errors.New(target + " is out of date, run optsgen to update it")
+		}
+
+		return nil
+	}
+
+	if target == "" {
+		_, err = stdout.Write(output)
+		return err
+	}
+
+	return ioutil.WriteFile(target, output, 0644)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func mainTestRun(args ...string) (int, string, string) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	status := run(append([]string{}, args...), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestRun_Generate(t *testing.T) {
+	status, stdout, stderr := mainTestRun("--spec", "testdata/cli.yaml")
+	require.Equal(t, 0, status, stderr)
+
+	golden, err := ioutil.ReadFile("options_gen_test.go")
+	require.Nil(t, err)
+	require.Equal(t, string(golden), stdout)
+}
+
+func TestRun_Check(t *testing.T) {
+	status, _, stderr := mainTestRun("--spec", "testdata/cli.yaml", "--out", "options_gen_test.go", "--check")
+	require.Equal(t, 0, status, stderr)
+
+	out := filepath.Join(t.TempDir(), "options_gen.go")
+	require.Nil(t, ioutil.WriteFile(out, []byte("package main\n"), 0644))
+	status, _, stderr = mainTestRun("-s", "testdata/cli.yaml", "-o", out, "--check")
+	require.Equal(t, 1, status)
+	require.Equal(t, "optsgen: "+out+" is out of date, run optsgen to update it\n", stderr)
+
+	status, _, _ = mainTestRun("-s", "testdata/cli.yaml", "-o", out)
+	require.Equal(t, 0, status)
+	status, _, _ = mainTestRun("-s", "testdata/cli.yaml", "-o", out, "--check")
+	require.Equal(t, 0, status)
+}
+
+func TestRun_Reverse(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "cli.json")
+	status, _, stderr := mainTestRun("--reverse", "--dir", "testdata/reverse", "--type", "Options", "--spec", spec)
+	require.Equal(t, 0, status, stderr)
+
+	data, err := ioutil.ReadFile(spec)
+	require.Nil(t, err)
+	decoded, err := DecodeSpec(spec, data)
+	require.Nil(t, err)
+	require.Equal(t, "Options", decoded.Type)
+
+	status, _, stderr = mainTestRun("--reverse", "--dir", "testdata/reverse", "--type", "Options", "--spec", spec, "--check")
+	require.Equal(t, 0, status, stderr)
+
+	status, _, stderr = mainTestRun("--reverse", "--spec", spec)
+	require.Equal(t, 1, status)
+	require.Equal(t, "optsgen: --type is required with --reverse\n", stderr)
+}
+
+func TestRun_Parser(t *testing.T) {
+	status, _, stderr := mainTestRun("--parser", "--dir", "../../internal/conformance", "--type", "Options", "--out", "../../internal/conformance/options_parser.go", "--check")
+	require.Equal(t, 0, status, stderr)
+
+	status, stdout, stderr := mainTestRun("-p", "--dir", "testdata/parser", "-t", "Options")
+	require.Equal(t, 0, status, stderr)
+	require.Contains(t, stdout, "func NewOptionsOptionSet(data *Options, settings ...opts.Setting) (*opts.OptionSet, error) {")
+
+	status, _, stderr = mainTestRun("--parser")
+	require.Equal(t, 1, status)
+	require.Equal(t, "optsgen: --type is required with --parser\n", stderr)
+}
+
+func TestRun_Errors(t *testing.T) {
+	status, _, stderr := mainTestRun()
+	require.Equal(t, 1, status)
+	require.Equal(t, "optsgen: Missing required option: --spec\n", stderr)
+
+	status, _, stderr = mainTestRun("--spec", "testdata/missing.yaml")
+	require.Equal(t, 1, status)
+	require.Contains(t, stderr, "missing.yaml")
+}
+
+func TestRun_Help(t *testing.T) {
+	status, stdout, _ := mainTestRun("--help")
+	require.Equal(t, 0, status)
+	require.Contains(t, stdout, "Usage: optsgen")
+	require.Contains(t, stdout, "  -s, --spec string\n")
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ronelliott/go-opts"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The types of basic fields options are bound to by opts.Bind
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:    reflect.TypeOf(false),
+	types.Float32: reflect.TypeOf(float32(0)),
+	types.Float64: reflect.TypeOf(float64(0)),
+	types.Int:     reflect.TypeOf(int(0)),
+	types.Int8:    reflect.TypeOf(int8(0)),
+	types.Int16:   reflect.TypeOf(int16(0)),
+	types.Int32:   reflect.TypeOf(int32(0)),
+	types.Int64:   reflect.TypeOf(int64(0)),
+	types.String:  reflect.TypeOf(""),
+	types.Uint:    reflect.TypeOf(uint(0)),
+	types.Uint8:   reflect.TypeOf(uint8(0)),
+	types.Uint16:  reflect.TypeOf(uint16(0)),
+	types.Uint32:  reflect.TypeOf(uint32(0)),
+	types.Uint64:  reflect.TypeOf(uint64(0)),
+}
+
+// Stands in for the types stored as text while the options of a struct are
+// checked, as their types only exist in the package of the struct
+type textValue []byte
+
+func (this textValue) MarshalText() ([]byte, error) {
+	return this, nil
+}
+
+func (this *textValue) UnmarshalText(text []byte) error {
+	*this = append(textValue{}, text...)
+	return nil
+}
+
+// Generates the parser of an options struct
+type parserGenerator struct {
+	// the expressions binding the fields of the options, keyed by the names
+	// of the options (i.e. "Database.Host")
+	bindings map[string]string
+
+	// true if a binding of a type stored as text uses a reflect.Kind
+	kinds bool
+
+	// reads the option tags of the fields
+	reader *reverseReader
+}
+
+// Generates the Go source of a function creating the option set of the
+// options struct type with the given name, declared in the package in the
+// given directory. The options are read and checked by opts.NewOptionSet when
+// generating, the function passes them to opts.NewStaticOptionSet with typed
+// bindings of their fields, so neither creating the set nor parsing reflects
+// on the struct or parses its tags. If a namespace is given, the option tags
+// are read from the tag with that key, like opts.WithTagNamespace.
+func GenerateParser(dir, typ, namespace string) ([]byte, error) {
+	name, reader, err := readPackage(dir, typ, namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	structType, err := reader.structType(name, typ)
+
+	if err != nil {
+		return nil, err
+	}
+
+	generator := parserGenerator{bindings: map[string]string{}, reader: reader}
+	dataType, err := generator.structType(structType, "data", "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// unnamed options are named by their field, they may be named by a
+	// naming strategy when the set is created
+	set, err := opts.NewOptionSet(
+		reflect.New(dataType).Interface(),
+		opts.WithEnv(func(string) (string, bool) { return "", false }),
+		opts.WithNaming(func(field string) string { return field }),
+		opts.WithTagNamespace(namespace))
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "// Code generated by optsgen --parser from %s. DO NOT EDIT.\n\n", typ)
+	fmt.Fprintf(&buf, "package %s\n\n", name)
+	buf.WriteString("import (\n\"github.com/ronelliott/go-opts\"\n")
+
+	if generator.kinds {
+		buf.WriteString("\"reflect\"\n")
+	}
+
+	buf.WriteString(")\n\n")
+	list := set.List()
+	variable := strings.ToLower(typ[:1]) + typ[1:] + "Options"
+	fmt.Fprintf(&buf, "// The options of %s, as read from the tags of its fields\n", typ)
+	fmt.Fprintf(&buf, "var %s = []opts.Option{\n", variable)
+
+	for _, opt := range list {
+		fmt.Fprintf(&buf, "%s,\n", optionLiteral(opt))
+	}
+
+	buf.WriteString("}\n\n")
+	fmt.Fprintf(&buf, "// Creates the OptionSet of the given %s, like opts.NewOptionSet but\n", typ)
+	buf.WriteString("// without reflecting on the struct or parsing its tags\n")
+	fmt.Fprintf(&buf, "func New%sOptionSet(data *%s, settings ...opts.Setting) (*opts.OptionSet, error) {\n", typ, typ)
+	fmt.Fprintf(&buf, "return opts.NewStaticOptionSet(%s, %s, []opts.Binding{\n", strconv.Quote(typ), variable)
+
+	for _, opt := range list {
+		fmt.Fprintf(&buf, "%s,\n", generator.bindings[opt.Name])
+	}
+
+	buf.WriteString("}, settings...)\n}\n")
+	return format.Source(buf.Bytes())
+}
+
+// Type checks the files of the package with the given name and returns the
+// struct type with the given name. Errors type checking the package, like
+// imports which cannot be read, are ignored, the types of the option fields
+// are checked when they are bound.
+func (this *reverseReader) structType(pkgName, name string) (*types.Struct, error) {
+	paths := []string{}
+
+	for path := range this.files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	files := []*ast.File{}
+
+	for _, path := range paths {
+		files = append(files, this.files[path])
+	}
+
+	config := types.Config{
+		Error:    func(error) {},
+		Importer: importer.ForCompiler(this.fset, "source", nil),
+	}
+
+	pkg, _ := config.Check(pkgName, this.fset, files, nil)
+
+	if obj, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+		if typ, ok := obj.Type().Underlying().(*types.Struct); ok {
+			return typ, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Struct type %s not found", name))
+}
+
+// Returns a struct type with the option fields of the given struct type, of
+// the types checked by opts.NewOptionSet in place of the types of the fields.
+// Binds the fields, selected from the given expression. Fields tagged with a
+// group are read recursively, like opts.NewOptionSet does.
+func (this *parserGenerator) structType(typ *types.Struct, expr, path string) (reflect.Type, error) {
+	fields := []reflect.StructField{}
+
+	for n := 0; n < typ.NumFields(); n++ {
+		field := typ.Field(n)
+		raw := typ.Tag(n)
+
+		// ignore field without tags
+		if raw == "" {
+			continue
+		}
+
+		name := joinPath(path, field.Name())
+		tags, err := this.reader.tags(raw)
+
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"Invalid tag for field %s: %s",
+				name,
+				err))
+		}
+
+		if tags == nil {
+			continue
+		}
+
+		if !field.Exported() {
+			return nil, errors.New("Cannot interface field address: " + field.Name())
+		}
+
+		var fieldType reflect.Type
+		fieldExpr := expr + "." + field.Name()
+
+		if nested, ok := field.Type().Underlying().(*types.Struct); ok && tags["group"] != "" {
+			fieldType, err = this.structType(nested, fieldExpr, name)
+		} else {
+			positional := tags["positional"] != "" && tags["positional"] != "false"
+			fieldType, err = this.bind(field.Type(), fieldExpr, name, positional)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, reflect.StructField{
+			Name: field.Name(),
+			Type: fieldType,
+			Tag:  reflect.StructTag(raw),
+		})
+	}
+
+	return reflect.StructOf(fields), nil
+}
+
+// Binds the option of the field with the given name and type, selected from
+// the given expression. Returns the type opts.NewOptionSet checks the option
+// with. Types stored as text can only be bound to positional options, like
+// opts.NewOptionSet.
+func (this *parserGenerator) bind(typ types.Type, expr, name string, positional bool) (reflect.Type, error) {
+	if basic, ok := typ.(*types.Basic); ok && basic.Kind() == types.Invalid {
+		return nil, errors.New("Cannot resolve the type of field " + name)
+	}
+
+	if basic, ok := basicType(typ); ok {
+		this.bindings[name] = fmt.Sprintf("opts.Bind(&%s)", expr)
+		return basic, nil
+	}
+
+	if slice, ok := typ.(*types.Slice); ok {
+		if basic, ok := basicType(slice.Elem()); ok {
+			this.bindings[name] = fmt.Sprintf("opts.Bind(&%s)", expr)
+			return reflect.SliceOf(basic), nil
+		}
+
+		if positional && isTextType(slice.Elem()) {
+			this.bindings[name] = fmt.Sprintf(
+				"opts.BindTexts(&%s, %s)",
+				expr,
+				strconv.Quote(typeString(typ)))
+			return reflect.TypeOf([]textValue{}), nil
+		}
+	}
+
+	if kind, ok := kindName(typ.Underlying()); ok && positional && isTextType(typ) {
+		this.kinds = true
+		this.bindings[name] = fmt.Sprintf(
+			"opts.BindText(&%s, %s, %s)",
+			expr,
+			strconv.Quote(typeString(typ)),
+			kind)
+		return reflect.TypeOf(textValue{}), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf(
+		"Type '%s' of field %s cannot be handled",
+		typeString(typ),
+		name))
+}
+
+// Returns the reflect type of the given type if opts.Bind binds it
+func basicType(typ types.Type) (reflect.Type, bool) {
+	switch typ := typ.(type) {
+	case *types.Basic:
+		basic, ok := basicTypes[typ.Kind()]
+		return basic, ok
+
+	case *types.Named:
+		obj := typ.Obj()
+
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Duration" {
+			return reflect.TypeOf(time.Duration(0)), true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns true if pointers to the given type implement
+// encoding.TextMarshaler and encoding.TextUnmarshaler
+func isTextType(typ types.Type) bool {
+	methods := types.NewMethodSet(types.NewPointer(typ))
+	return methods.Lookup(nil, "MarshalText") != nil &&
+		methods.Lookup(nil, "UnmarshalText") != nil
+}
+
+// Returns the name of the reflect.Kind constant of the given underlying type
+// (i.e. "reflect.Slice")
+func kindName(typ types.Type) (string, bool) {
+	var kind reflect.Kind
+
+	switch typ := typ.(type) {
+	case *types.Basic:
+		basic, ok := basicTypes[typ.Kind()]
+
+		if !ok {
+			return "", false
+		}
+
+		kind = basic.Kind()
+	case *types.Array:
+		kind = reflect.Array
+	case *types.Map:
+		kind = reflect.Map
+	case *types.Pointer:
+		kind = reflect.Ptr
+	case *types.Slice:
+		kind = reflect.Slice
+	case *types.Struct:
+		kind = reflect.Struct
+	default:
+		return "", false
+	}
+
+	name := kind.String()
+	return "reflect." + strings.ToUpper(name[:1]) + name[1:], true
+}
+
+// Returns the given type as reflect names it, qualified by package name (i.e.
+// "[]net.IP")
+func typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+}
+
+// Returns the given option as a Go composite literal of an opts.Option,
+// without the type as it is an element of a slice, with the fields read from
+// its tags. The kind, type, source and the values read
+// when the option is bound are left to opts.NewStaticOptionSet.
+func optionLiteral(opt *opts.Option) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("{\n")
+
+	add := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s,\n", name, value)
+	}
+
+	addString := func(name, value string) {
+		if value != "" {
+			add(name, strconv.Quote(value))
+		}
+	}
+
+	addBool := func(name string, value bool) {
+		if value {
+			add(name, "true")
+		}
+	}
+
+	addList := func(name string, values []string) {
+		if len(values) > 0 {
+			add(name, stringsLiteral(values))
+		}
+	}
+
+	addList("Aliases", opt.Aliases)
+	addString("ArgName", opt.ArgName)
+	addList("Choices", opt.Choices)
+	addBool("Counter", opt.Counter)
+	addString("Default", opt.Tags["default"])
+	addString("Deprecated", opt.Deprecated)
+	addList("DeprecatedAliases", opt.DeprecatedAliases)
+	addString("Description", opt.Description)
+	addString("Env", opt.Env)
+	addString("Group", opt.Group)
+	addString("Help", opt.Help)
+	addBool("Hidden", opt.Hidden)
+	addString("Long", opt.Tags["long"])
+	addString("Max", opt.Max)
+	addString("Min", opt.Min)
+	addString("Name", opt.Name)
+	addBool("Negatable", opt.Negatable)
+	addString("Pattern", opt.Pattern)
+
+	if opt.Position > 0 {
+		add("Position", strconv.Itoa(opt.Position))
+	}
+
+	addBool("Required", opt.Required)
+	addBool("Secret", opt.Secret)
+	addString("Short", opt.Short)
+	add("Tags", tagSetLiteral(opt.Tags))
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// Returns the given strings as a Go composite literal
+func stringsLiteral(values []string) string {
+	quoted := make([]string, len(values))
+
+	for n, value := range values {
+		quoted[n] = strconv.Quote(value)
+	}
+
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// Returns the given tags as a Go composite literal, with sorted keys
+func tagSetLiteral(tags opts.TagSet) string {
+	keys := []string{}
+
+	for key := range tags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+
+	for n, key := range keys {
+		pairs[n] = strconv.Quote(key) + ": " + strconv.Quote(tags[key])
+	}
+
+	return "opts.TagSet{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Joins the given path and name with a dot, i.e. "Database.Host"
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
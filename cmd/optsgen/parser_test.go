@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerateParser(t *testing.T) {
+	output, err := GenerateParser("../../internal/conformance", "Options", "")
+	require.Nil(t, err)
+
+	current, err := ioutil.ReadFile("../../internal/conformance/options_parser.go")
+	require.Nil(t, err)
+	require.Equal(t, string(current), string(output))
+}
+
+func TestGenerateParser_Fields(t *testing.T) {
+	output, err := GenerateParser("testdata/parser", "Options", "")
+	require.Nil(t, err)
+	require.Contains(t, string(output), "import (\n\t\"github.com/ronelliott/go-opts\"\n)\n")
+	require.Contains(t, string(output), "var optionsOptions = []opts.Option{\n"+
+		"\t{\n"+
+		"\t\tArgName: \"DEBUG\",\n"+
+		"\t\tGroup:   \"base\",\n"+
+		"\t\tLong:    \"debug\",\n"+
+		"\t\tName:    \"Base.Debug\",\n"+
+		"\t\tTags:    opts.TagSet{\"long\": \"debug\"},\n")
+	require.Contains(t, string(output), "\treturn opts.NewStaticOptionSet(\"Options\", optionsOptions, []opts.Binding{\n"+
+		"\t\topts.Bind(&data.Base.Debug),\n")
+	require.NotContains(t, string(output), "Plain")
+
+	output, err = GenerateParser("testdata/parser", "Options", "opts")
+	require.Nil(t, err)
+	require.Contains(t, string(output), "import (\n\t\"github.com/ronelliott/go-opts\"\n\t\"reflect\"\n)\n")
+	require.Contains(t, string(output), "\t\topts.Bind(&data.Name),\n")
+	require.Contains(t, string(output), "\t\topts.Bind(&data.Timeout),\n")
+	require.Contains(t, string(output), "\t{\n"+
+		"\t\tArgName: \"HOST\",\n"+
+		"\t\tGroup:   \"inline\",\n"+
+		"\t\tLong:    \"host\",\n"+
+		"\t\tName:    \"Inline.Host\",\n"+
+		"\t\tTags:    opts.TagSet{\"long\": \"host\"},\n")
+	require.Contains(t, string(output), "\t\topts.Bind(&data.Inline.Host),\n")
+	require.Contains(t, string(output), "\t\topts.BindText(&data.Level, \"app.Level\", reflect.Int),\n")
+	require.Contains(t, string(output), "\t\topts.BindTexts(&data.Addrs, \"[]net.IP\"),\n")
+	require.NotContains(t, string(output), "Debug")
+	require.NotContains(t, string(output), "Skipped")
+	require.NotContains(t, string(output), "Plain")
+}
+
+func TestGenerateParser_Errors(t *testing.T) {
+	_, err := GenerateParser("testdata/parser", "Unexported", "")
+	require.Equal(t, "Cannot interface field address: name", err.Error())
+
+	_, err = GenerateParser("testdata/parser", "Invalid", "")
+	require.Equal(t, "Invalid tag for field Name: Invalid tag at position 5: expected '\"' to start the value of 'long'", err.Error())
+
+	_, err = GenerateParser("testdata/parser", "Unsupported", "")
+	require.Equal(t, "Type 'map[string]string' of field Values cannot be handled", err.Error())
+
+	_, err = GenerateParser("testdata/parser", "TextFlag", "")
+	require.Equal(t, "Type 'net.IP' of field Addr cannot be handled", err.Error())
+
+	_, err = GenerateParser("testdata/parser", "Conflict", "")
+	require.Equal(t, "Invalid option definitions:\n  Flag 'name' is defined by options 'Name' and 'Title'", err.Error())
+
+	_, err = GenerateParser("testdata/parser", "Missing", "")
+	require.Equal(t, "Type Missing not found in testdata/parser", err.Error())
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ronelliott/go-opts"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reads the spec of the options struct type with the given name from the Go
+// files of the package in the given directory. Only the struct tags are read,
+// so defaults set in code are not part of the spec. If a namespace is given,
+// the option tags are read from the tag with that key, like
+// opts.WithTagNamespace.
+func Reverse(dir, typ, namespace string) (*Spec, error) {
+	name, reader, err := readPackage(dir, typ, namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := reader.read(typ)
+
+	if err != nil {
+		return nil, err
+	}
+
+	spec := Spec{Package: name, Type: typ, Options: options}
+
+	for path := range reader.imports {
+		if path != "time" {
+			spec.Imports = append(spec.Imports, path)
+		}
+	}
+
+	sort.Strings(spec.Imports)
+	return &spec, nil
+}
+
+// Parses the Go files of the package in the given directory which declares
+// the struct type with the given name. Returns the name of the package and a
+// reader for its files.
+func readPackage(dir, typ, namespace string) (string, *reverseReader, error) {
+	fset := token.NewFileSet()
+	notTest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+
+	pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	names := []string{}
+
+	for name := range pkgs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		reader := reverseReader{
+			files:     pkgs[name].Files,
+			fset:      fset,
+			namespace: namespace,
+			imports:   map[string]bool{},
+		}
+
+		if found, _ := reader.find(typ); found != nil {
+			return name, &reader, nil
+		}
+	}
+
+	return "", nil, errors.New(fmt.Sprintf("Type %s not found in %s", typ, dir))
+}
+
+// Reads the specs of options structs from the files of a package
+type reverseReader struct {
+	// the files of the package, keyed by path
+	files map[string]*ast.File
+
+	// the positions of the files of the package
+	fset *token.FileSet
+
+	// the tag key option tags are read from, empty to read the field tags
+	namespace string
+
+	// the import paths of the packages used by option types
+	imports map[string]bool
+}
+
+// Returns the struct type with the given name and the file it is declared
+// in, nil if there is no such type
+func (this *reverseReader) find(name string) (*ast.StructType, *ast.File) {
+	for _, file := range this.files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+
+				if typ, ok := typeSpec.Type.(*ast.StructType); ok && typeSpec.Name.Name == name {
+					return typ, file
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Reads the options of the struct type with the given name
+func (this *reverseReader) read(name string) ([]*OptionSpec, error) {
+	typ, file := this.find(name)
+
+	if typ == nil {
+		return nil, errors.New(fmt.Sprintf("Struct type %s not found", name))
+	}
+
+	options := []*OptionSpec{}
+
+	for _, field := range typ.Fields.List {
+		if field.Tag == nil || len(field.Names) == 0 {
+			continue
+		}
+
+		raw, err := strconv.Unquote(field.Tag.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tags, err := this.tags(raw)
+
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf(
+				"Invalid tag for field %s of %s: %s",
+				field.Names[0].Name,
+				name,
+				err))
+		}
+
+		if tags == nil {
+			continue
+		}
+
+		this.addImports(field.Type, file)
+
+		for _, ident := range field.Names {
+			opt := optionSpec(ident.Name, types.ExprString(field.Type), tags)
+
+			if opt.Group != "" {
+				opt.Options, err = this.read(opt.Type)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			options = append(options, opt)
+		}
+	}
+
+	return options, nil
+}
+
+// Returns the option tags in the given raw field tag, nil if the field is not
+// an option
+func (this *reverseReader) tags(raw string) (opts.TagSet, error) {
+	tags, err := opts.ParseTagSet(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if this.namespace != "" {
+		value, ok := tags[this.namespace]
+
+		if !ok || value == "-" {
+			return nil, nil
+		}
+
+		return opts.ParseNamespacedTag(value)
+	}
+
+	// fields only tagged for other packages, like json, are left out
+	if len(tags.UnknownKeys()) == len(tags) {
+		return nil, nil
+	}
+
+	return tags, nil
+}
+
+// Records the import paths of the packages used by the given field type
+func (this *reverseReader) addImports(expr ast.Expr, file *ast.File) {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		selector, ok := node.(*ast.SelectorExpr)
+
+		if !ok {
+			return true
+		}
+
+		pkg, ok := selector.X.(*ast.Ident)
+
+		if !ok {
+			return true
+		}
+
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+
+			if name == pkg.Name {
+				this.imports[path] = true
+			}
+		}
+
+		return false
+	})
+}
+
+// Returns the spec of the option stored in the field with the given name and
+// type, defined by the given tags
+func optionSpec(field, typ string, tags opts.TagSet) *OptionSpec {
+	opt := OptionSpec{
+		Field:             field,
+		Type:              typ,
+		Long:              tags["long"],
+		Short:             tags["short"],
+		Aliases:           splitAliases(tags["alias"] + "," + tags["aliases"]),
+		ArgName:           tags["name"],
+		Positional:        tags["positional"],
+		Required:          tags["required"] == "true",
+		Default:           tags["default"],
+		Env:               tags["env"],
+		Choices:           splitList(tags["choices"]),
+		Min:               tags["min"],
+		Max:               tags["max"],
+		Pattern:           tags["pattern"],
+		Count:             tags["count"] == "true",
+		Negatable:         tags["negatable"] == "true",
+		Hidden:            tags["hidden"] == "true",
+		Secret:            tags["secret"] == "true",
+		Deprecated:        tags["deprecated"],
+		DeprecatedAliases: splitAliases(tags["deprecated-aliases"]),
+		Description:       tags["description"],
+		Help:              tags["help"],
+		Group:             tags["group"],
+	}
+
+	if opt.Positional == "false" {
+		opt.Positional = ""
+	}
+
+	if value, ok := tags["reloadable"]; ok {
+		reloadable := value != "false"
+		opt.Reloadable = &reloadable
+	}
+
+	return &opt
+}
+
+// Splits the given comma separated list, leaving out empty items
+func splitList(raw string) []string {
+	var items []string
+
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Splits the given comma separated list of aliases, which may be dashed (i.e.
+// "--dry,-N")
+func splitAliases(raw string) []string {
+	var aliases []string
+
+	for _, alias := range splitList(raw) {
+		if alias = strings.TrimLeft(alias, "-"); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
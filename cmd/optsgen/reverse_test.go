@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	spec, err := Reverse("testdata/reverse", "Options", "")
+	require.Nil(t, err)
+
+	reloadable := true
+	require.Equal(t, &Spec{
+		Package: "app",
+		Type:    "Options",
+		Imports: []string{"net"},
+		Options: []*OptionSpec{
+			{Field: "Name", Type: "string", Long: "name", Short: "n", Aliases: []string{"title", "T"}, Default: "foo"},
+			{Field: "Timeout", Type: "time.Duration", Long: "timeout", Reloadable: &reloadable},
+			{Field: "Addr", Type: "ip.IP", Positional: "1"},
+			{Field: "Hosts", Type: "[]string", Positional: "true"},
+			{Field: "Backups", Type: "[]string", Positional: "true"},
+			{Field: "Database", Type: "DatabaseOptions", Group: "database", Options: []*OptionSpec{
+				{Field: "Host", Type: "string", Long: "db-host", Env: "DB_HOST"},
+			}},
+		},
+	}, spec)
+}
+
+func TestReverse_Namespace(t *testing.T) {
+	spec, err := Reverse("testdata/reverse", "Tagged", "opts")
+	require.Nil(t, err)
+	require.Equal(t, []*OptionSpec{
+		{Field: "Name", Type: "string", Long: "name", Short: "n", Choices: []string{"a", "b"}},
+	}, spec.Options)
+}
+
+func TestReverse_NotFound(t *testing.T) {
+	_, err := Reverse("testdata/reverse", "Ignored", "")
+	require.Equal(t, "Type Ignored not found in testdata/reverse", err.Error())
+
+	_, err = Reverse("testdata/missing", "Options", "")
+	require.NotNil(t, err)
+}
+
+func TestReverse_RoundTrip(t *testing.T) {
+	spec := specTestRead(t)
+	output, err := Generate(spec, "testdata/cli.yaml")
+	require.Nil(t, err)
+
+	dir := t.TempDir()
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "options_gen.go"), output, 0644))
+
+	reversed, err := Reverse(dir, "CLIOptions", "")
+	require.Nil(t, err)
+	require.Equal(t, spec, reversed)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"strings"
+)
+
+// The types of options given as flags
+var flagTypes = map[string]bool{
+	"bool":          true,
+	"float64":       true,
+	"int":           true,
+	"int64":         true,
+	"string":        true,
+	"time.Duration": true,
+	"uint":          true,
+	"uint64":        true,
+}
+
+// The predeclared types positional args cannot be parsed into
+var unsupportedTypes = map[string]bool{
+	"any":        true,
+	"complex128": true,
+	"complex64":  true,
+	"error":      true,
+	"uintptr":    true,
+}
+
+// The description of an options struct shared with tools not written in Go
+type Spec struct {
+	// the package of the generated code
+	Package string `json:"package" yaml:"package"`
+
+	// the name of the options struct type
+	Type string `json:"type" yaml:"type"`
+
+	// the import paths of the packages used by option types, "time" is
+	// imported automatically
+	Imports []string `json:"imports,omitempty" yaml:"imports,omitempty"`
+
+	// the options, in the order of the struct fields
+	Options []*OptionSpec `json:"options" yaml:"options"`
+}
+
+// The description of a single option, or of a group of options
+type OptionSpec struct {
+	// the name of the struct field
+	Field string `json:"field" yaml:"field"`
+
+	// the Go type of the field, the struct type of groups
+	Type string `json:"type" yaml:"type"`
+
+	// the long flag name
+	Long string `json:"long,omitempty" yaml:"long,omitempty"`
+
+	// the short flag name
+	Short string `json:"short,omitempty" yaml:"short,omitempty"`
+
+	// additional flag names
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+
+	// the name of the positional arg shown in usage lines
+	ArgName string `json:"arg_name,omitempty" yaml:"arg_name,omitempty"`
+
+	// the positional index, or "true" for options storing the leftover args
+	Positional string `json:"positional,omitempty" yaml:"positional,omitempty"`
+
+	// true if the option must be given
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
+
+	// the default value
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// the environment variable the default is read from
+	Env string `json:"env,omitempty" yaml:"env,omitempty"`
+
+	// the values the option can be set to
+	Choices []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+
+	// the smallest value of a number option
+	Min string `json:"min,omitempty" yaml:"min,omitempty"`
+
+	// the largest value of a number option
+	Max string `json:"max,omitempty" yaml:"max,omitempty"`
+
+	// the regular expression the values of a string option must match
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// true if each use of the option increments it
+	Count bool `json:"count,omitempty" yaml:"count,omitempty"`
+
+	// true if a bool option can be turned off with "--no-<long>"
+	Negatable bool `json:"negatable,omitempty" yaml:"negatable,omitempty"`
+
+	// true if the option is left out of the help output
+	Hidden bool `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+
+	// true if the value is hidden and redacted
+	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// false if the option rejects changes made by reloads
+	Reloadable *bool `json:"reloadable,omitempty" yaml:"reloadable,omitempty"`
+
+	// the deprecation notice, "true" for deprecated options without one
+	Deprecated string `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	// the deprecated aliases, all names are deprecated if empty
+	DeprecatedAliases []string `json:"deprecated_aliases,omitempty" yaml:"deprecated_aliases,omitempty"`
+
+	// the short description shown in the help output
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// the long help
+	Help string `json:"help,omitempty" yaml:"help,omitempty"`
+
+	// the name of the group, for fields holding a nested options struct
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+
+	// the options of the group
+	Options []*OptionSpec `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Returns true if the spec at the given path is written as YAML, by extension
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Decodes the given spec data, as YAML if the given path has a YAML extension
+// or as JSON otherwise. The spec is validated.
+func DecodeSpec(path string, data []byte) (*Spec, error) {
+	spec := Spec{}
+	var err error
+
+	if isYAML(path) {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&spec)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&spec)
+	}
+
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Cannot decode spec %s: %s", path, err))
+	}
+
+	err = spec.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Encodes the given spec, as YAML if the given path has a YAML extension or
+// as JSON otherwise
+func EncodeSpec(path string, spec *Spec) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	if isYAML(path) {
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		err := encoder.Encode(spec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), encoder.Close()
+	}
+
+	data, err := json.MarshalIndent(spec, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Checks the spec describes a valid options struct
+func (this *Spec) Validate() error {
+	problems := []string{}
+
+	if this.Package == "" {
+		problems = append(problems, "Spec has no package")
+	}
+
+	if this.Type == "" {
+		problems = append(problems, "Spec has no type")
+	}
+
+	types := map[string]bool{this.Type: true}
+	problems = validateOptions(this.Options, this.Type, types, problems)
+
+	if len(problems) > 0 {
+		return errors.New("Invalid spec:\n  " + strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// Checks the given options of the given struct type, appending the problems
+// found to the given ones
+func validateOptions(options []*OptionSpec, typ string, types map[string]bool, problems []string) []string {
+	fields := map[string]bool{}
+
+	for _, opt := range options {
+		if !isIdentifier(opt.Field) {
+			problems = append(problems, fmt.Sprintf(
+				"Invalid field name '%s' in %s",
+				opt.Field,
+				typ))
+		} else if fields[opt.Field] {
+			problems = append(problems, fmt.Sprintf(
+				"Field %s is defined more than once in %s",
+				opt.Field,
+				typ))
+		}
+
+		fields[opt.Field] = true
+
+		if opt.Group == "" && len(opt.Options) > 0 {
+			problems = append(problems, fmt.Sprintf(
+				"Field %s of %s has options but no group",
+				opt.Field,
+				typ))
+		}
+
+		if opt.Group == "" {
+			if _, err := parser.ParseExpr(opt.Type); err != nil || opt.Type == "" {
+				problems = append(problems, fmt.Sprintf(
+					"Invalid type '%s' for field %s of %s",
+					opt.Type,
+					opt.Field,
+					typ))
+			} else if !isSupportedType(opt.Type, opt.isPositional()) {
+				problems = append(problems, fmt.Sprintf(
+					"Type '%s' of field %s of %s cannot be handled",
+					opt.Type,
+					opt.Field,
+					typ))
+			}
+
+			continue
+		}
+
+		if !isIdentifier(opt.Type) || types[opt.Type] {
+			problems = append(problems, fmt.Sprintf(
+				"Invalid or duplicate group type '%s' for field %s of %s",
+				opt.Type,
+				opt.Field,
+				typ))
+			continue
+		}
+
+		types[opt.Type] = true
+		problems = validateOptions(opt.Options, opt.Type, types, problems)
+	}
+
+	return problems
+}
+
+// Returns true if the option is a positional arg
+func (this *OptionSpec) isPositional() bool {
+	return this.Positional != "" && this.Positional != "false"
+}
+
+// Returns true if the opts package can parse values of the given type, which
+// must be a valid Go expression. Flags have a fixed set of types. Positional
+// args can also be other numbers, slices, and named types, which are expected
+// to implement encoding.TextUnmarshaler.
+func isSupportedType(typ string, positional bool) bool {
+	if !positional {
+		return flagTypes[typ]
+	}
+
+	expr, _ := parser.ParseExpr(strings.TrimPrefix(typ, "[]"))
+
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return !unsupportedTypes[expr.Name]
+	case *ast.SelectorExpr:
+		return true
+	}
+
+	return false
+}
+
+// Returns true if the given name is a valid Go identifier
+func isIdentifier(name string) bool {
+	expr, err := parser.ParseExpr(name)
+	_, ok := expr.(*ast.Ident)
+	return err == nil && ok
+}
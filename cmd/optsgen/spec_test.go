@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+func specTestRead(t *testing.T) *Spec {
+	data, err := ioutil.ReadFile("testdata/cli.yaml")
+	require.Nil(t, err)
+	spec, err := DecodeSpec("testdata/cli.yaml", data)
+	require.Nil(t, err)
+	return spec
+}
+
+func TestDecodeSpec(t *testing.T) {
+	spec := specTestRead(t)
+	require.Equal(t, "main", spec.Package)
+	require.Equal(t, "CLIOptions", spec.Type)
+	require.Len(t, spec.Options, 10)
+	require.Equal(t, []string{"title"}, spec.Options[0].Aliases)
+	require.False(t, *spec.Options[1].Reloadable)
+	require.Equal(t, "db-host", spec.Options[6].Options[0].Long)
+}
+
+func TestDecodeSpec_JSON(t *testing.T) {
+	spec, err := DecodeSpec("cli.json", []byte(`{"package": "main", "type": "Options", "options": [{"field": "Name", "type": "string", "long": "name"}]}`))
+	require.Nil(t, err)
+	require.Equal(t, "name", spec.Options[0].Long)
+
+	_, err = DecodeSpec("cli.json", []byte(`{"package": "main", "type": "Options", "nope": 1}`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Cannot decode spec cli.json")
+
+	_, err = DecodeSpec("cli.yml", []byte("package: main\ntype: Options\nnope: 1\n"))
+	require.NotNil(t, err)
+}
+
+func TestSpecValidate(t *testing.T) {
+	spec := Spec{Options: []*OptionSpec{
+		{Field: "Name", Type: "string"},
+		{Field: "Name", Type: "string"},
+		{Field: "bad name", Type: "string"},
+		{Field: "Type", Type: "map[string"},
+		{Field: "Addr", Type: "net.IP", Long: "addr"},
+		{Field: "Small", Type: "int32", Long: "small"},
+		{Field: "Pairs", Type: "map[string]string", Positional: "1"},
+		{Field: "Host", Type: "net.IP", Positional: "2"},
+		{Field: "Ports", Type: "[]int32", Positional: "3"},
+		{Field: "Orphans", Type: "string", Options: []*OptionSpec{{Field: "A", Type: "int"}}},
+		{Field: "Group", Type: "Spec", Group: "group"},
+		{Field: "Other", Type: "Group", Group: "other", Options: []*OptionSpec{{Field: "B", Type: ""}}},
+		{Field: "Again", Type: "Spec", Group: "again"},
+	}}
+
+	err := spec.Validate()
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid spec:\n"+
+		"  Spec has no package\n"+
+		"  Spec has no type\n"+
+		"  Field Name is defined more than once in \n"+
+		"  Invalid field name 'bad name' in \n"+
+		"  Invalid type 'map[string' for field Type of \n"+
+		"  Type 'net.IP' of field Addr of  cannot be handled\n"+
+		"  Type 'int32' of field Small of  cannot be handled\n"+
+		"  Type 'map[string]string' of field Pairs of  cannot be handled\n"+
+		"  Field Orphans of  has options but no group\n"+
+		"  Invalid type '' for field B of Group\n"+
+		"  Invalid or duplicate group type 'Spec' for field Again of ",
+		err.Error())
+}
+
+func TestEncodeSpec(t *testing.T) {
+	spec := specTestRead(t)
+
+	for _, path := range []string{"cli.yaml", "cli.json"} {
+		data, err := EncodeSpec(path, spec)
+		require.Nil(t, err)
+		decoded, err := DecodeSpec(path, data)
+		require.Nil(t, err)
+		require.Equal(t, spec, decoded)
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+type Base struct {
+	Debug bool `long:"debug"`
+}
+
+type Level int
+
+func (this Level) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(this))), nil
+}
+
+func (this *Level) UnmarshalText(text []byte) error {
+	level, err := strconv.Atoi(string(text))
+	*this = Level(level)
+	return err
+}
+
+type Options struct {
+	Base `group:"base"`
+
+	Name string `opts:"long=name"`
+
+	Timeout time.Duration `opts:"long=timeout,default=1s"`
+
+	Inline struct {
+		Host string `opts:"long=host"`
+	} `opts:"group=inline"`
+
+	Skipped string `opts:"-"`
+
+	Plain string `json:"plain"`
+
+	Level Level `opts:"positional=1"`
+
+	Addrs []net.IP `opts:"positional=true"`
+}
+
+type Unexported struct {
+	name string `long:"name"`
+}
+
+type Invalid struct {
+	Name string `long:name`
+}
+
+type Unsupported struct {
+	Values map[string]string `long:"values"`
+}
+
+type TextFlag struct {
+	Addr net.IP `long:"addr"`
+}
+
+type Conflict struct {
+	Name string `long:"name"`
+
+	Title string `long:"name"`
+}
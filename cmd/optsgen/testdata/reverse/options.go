@@ -0,0 +1,32 @@
+package app
+
+import (
+	ip "net"
+	"time"
+)
+
+type Options struct {
+	Name string `json:"name" long:"name" short:"n" aliases:"--title,-T" default:"foo"`
+
+	Timeout time.Duration `long:"timeout" reloadable:"true"`
+
+	Addr ip.IP `positional:"1"`
+
+	Hosts, Backups []string `positional:"true"`
+
+	Database DatabaseOptions `group:"database"`
+
+	Internal string `json:"internal"`
+
+	untagged int
+}
+
+type DatabaseOptions struct {
+	Host string `long:"db-host" env:"DB_HOST"`
+}
+
+type Tagged struct {
+	Name  string `json:"name" opts:"long=name,short=n,choices=a\\,b"`
+	Other string `json:"other" opts:"-"`
+	Plain string `json:"plain"`
+}
@@ -0,0 +1,3 @@
+package app
+
+type Ignored struct{}
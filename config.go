@@ -0,0 +1,195 @@
+package opts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// A raw option value read from a config file
+type configValue struct {
+	// the path of the config file the value was read from
+	file string
+
+	// the raw value
+	raw string
+}
+
+// Reads the values of the options in the config files of this set, later
+// files overriding earlier ones. Positional options are not read from config
+// files.
+func (this *OptionSet) readConfigFiles() (map[*Option]configValue, error) {
+	values := map[*Option]configValue{}
+
+	for _, path := range this.configFiles {
+		err := this.readConfigFile(path, values)
+
+		if err != nil {
+			return nil, &ConfigFileError{File: path, Err: err}
+		}
+	}
+
+	return values, nil
+}
+
+// Reads the option values in the JSON config file at the given path into the
+// given map. Grouped options are read from nested objects, the same way
+// WriteJSON writes them.
+func (this *OptionSet) readConfigFile(path string, values map[*Option]configValue) error {
+	data, err := fs.ReadFile(this.fsys, path)
+
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	config := map[string]interface{}{}
+	err = decoder.Decode(&config)
+
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]*Option{}
+	groups := map[string]bool{}
+
+	for _, opt := range this.order {
+		keys[joinPath(opt.Group, opt.dumpKey())] = opt
+
+		for group := opt.Group; group != ""; group = parentPath(group) {
+			groups[group] = true
+		}
+	}
+
+	reader := configReader{file: path, groups: groups, keys: keys, values: values}
+	return reader.read(config, "")
+}
+
+// Reads the option values of a decoded config file
+type configReader struct {
+	// the path of the config file
+	file string
+
+	// the group paths of the options, i.e. "Database.Pool"
+	groups map[string]bool
+
+	// the options, keyed by their group path and dump key (i.e. "database.host")
+	keys map[string]*Option
+
+	// the values read
+	values map[*Option]configValue
+}
+
+// Reads the values of the given config object, found at the given group path
+func (this *configReader) read(config map[string]interface{}, group string) error {
+	for key, value := range config {
+		path := joinPath(group, key)
+		nested, isObject := value.(map[string]interface{})
+
+		if isObject && this.groups[path] {
+			err := this.read(nested, path)
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		opt := this.keys[path]
+
+		if opt == nil {
+			return errors.New(fmt.Sprintf("Unknown option '%s'", path))
+		}
+
+		// positional args are written by WriteJSON, but not read
+		if opt.IsPositional() {
+			continue
+		}
+
+		switch value := value.(type) {
+		case bool:
+			this.values[opt] = configValue{this.file, strconv.FormatBool(value)}
+		case json.Number:
+			this.values[opt] = configValue{this.file, value.String()}
+		case string:
+			this.values[opt] = configValue{this.file, value}
+		case nil:
+			delete(this.values, opt)
+		default:
+			return errors.New(fmt.Sprintf(
+				"Expected a single value for option '%s'",
+				path))
+		}
+	}
+
+	return nil
+}
+
+// Sets the options which still have their default value to the values read
+// from the config files of this set
+func (this *OptionSet) applyConfigFiles() error {
+	if len(this.configFiles) == 0 {
+		return nil
+	}
+
+	values, err := this.readConfigFiles()
+
+	if err != nil {
+		return err
+	}
+
+	for _, opt := range this.order {
+		value, ok := values[opt]
+
+		if !ok || opt.Source != SourceDefault {
+			continue
+		}
+
+		err = this.setLiteral(opt.FlagNames()[0], value.raw)
+
+		if err != nil {
+			return &ConfigFileError{
+				File: value.file,
+				Err:  optionValueError(opt, value.raw, err),
+			}
+		}
+
+		opt.Source = SourceConfig
+	}
+
+	return nil
+}
+
+// Returns the path of the parent of the given group path, i.e. "Database" for
+// "Database.Pool"
+func parentPath(path string) string {
+	n := strings.LastIndex(path, ".")
+
+	if n < 0 {
+		return ""
+	}
+
+	return path[:n]
+}
+
+// Returns the error for the given invalid raw value of the given option.
+// Secret values are redacted.
+func optionValueError(opt *Option, raw string, err error) error {
+	err = errors.New(fmt.Sprintf(
+		"Invalid value '%s' for %s: %s",
+		raw,
+		opt.displayName(),
+		err.Error()))
+
+	if opt.Secret {
+		return redactError(err, raw)
+	}
+
+	return err
+}
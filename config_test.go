@@ -0,0 +1,143 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestConfigStruct struct {
+	Level    string                   `long:"level" default:"info" choices:"debug,info,warn"`
+	Timeout  time.Duration            `long:"timeout" default:"1s"`
+	Port     int                      `long:"port" env:"OPTS_TEST_CONFIG_PORT" reloadable:"false"`
+	Verbose  bool                     `short:"v"`
+	Token    string                   `long:"token" secret:"true"`
+	Database TestConfigDatabaseStruct `group:"database"`
+	Files    []string                 `positional:"true"`
+}
+
+type TestConfigDatabaseStruct struct {
+	Host string `long:"db-host" default:"localhost"`
+}
+
+func configTestWrite(t *testing.T, path, data string) string {
+	require.Nil(t, ioutil.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestOptionSetParse_ConfigFile(t *testing.T) {
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"),
+		`{"level": "debug", "timeout": "5s", "Verbose": true, "port": 80, "database": {"db-host": "db"}}`)
+
+	opts := TestConfigStruct{}
+	set, err := NewOptionSet(&opts, WithConfigFile(path))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--timeout", "2s", "a.txt"}))
+	require.Equal(t, "debug", opts.Level)
+	require.Equal(t, 2*time.Second, opts.Timeout)
+	require.Equal(t, 80, opts.Port)
+	require.True(t, opts.Verbose)
+	require.Equal(t, "db", opts.Database.Host)
+	require.Equal(t, []string{"a.txt"}, opts.Files)
+	require.Equal(t, SourceConfig, set.Options["Level"].Source)
+	require.Equal(t, SourceFlag, set.Options["Timeout"].Source)
+	require.Equal(t, SourceConfig, set.Options["Database.Host"].Source)
+}
+
+func TestOptionSetParse_ConfigFileOverrides(t *testing.T) {
+	dir := t.TempDir()
+	first := configTestWrite(t, filepath.Join(dir, "first.json"), `{"level": "debug", "port": 80}`)
+	second := configTestWrite(t, filepath.Join(dir, "second.json"), `{"level": "warn", "port": null}`)
+
+	os.Setenv("OPTS_TEST_CONFIG_PORT", "90")
+	defer os.Unsetenv("OPTS_TEST_CONFIG_PORT")
+
+	opts := TestConfigStruct{}
+	set, err := NewOptionSet(&opts, WithConfigFile(first), WithConfigFile(second))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "warn", opts.Level)
+	require.Equal(t, 90, opts.Port)
+	require.Equal(t, SourceEnv, set.Options["Port"].Source)
+}
+
+func TestOptionSetParse_ConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		`{"nope": 1}`:                   "Unknown option 'nope'",
+		`{"database": {"nope": 1}}`:     "Unknown option 'database.nope'",
+		`{"level": ["debug"]}`:          "Expected a single value for option 'level'",
+		`{"port": "eighty"}`:            "Invalid value 'eighty' for --port: parse error",
+		`{"token": 1, "port": 1.5}`:     "Invalid value '1.5' for --port: parse error",
+		`{"level": "trace"}`:            "",
+		`{"level": `:                    "unexpected EOF",
+		`{"Files": ["x"], "port": "x"}`: "Invalid value 'x' for --port: parse error",
+	}
+
+	for data, msg := range tests {
+		path := configTestWrite(t, filepath.Join(dir, "config.json"), data)
+		set, err := NewOptionSet(&TestConfigStruct{}, WithConfigFile(path))
+		require.Nil(t, err)
+		err = set.Parse([]string{})
+		require.NotNil(t, err, data)
+
+		if msg != "" {
+			require.IsType(t, &ConfigFileError{}, err, data)
+			require.Equal(t, "Invalid config file "+path+": "+msg, err.Error(), data)
+		}
+	}
+
+	set, err := NewOptionSet(&TestConfigStruct{}, WithConfigFile(filepath.Join(dir, "missing.json")))
+	require.Nil(t, err)
+	require.IsType(t, &ConfigFileError{}, set.Parse([]string{}))
+}
+
+func TestOptionSetParse_ConfigFileSecretRedacted(t *testing.T) {
+	data := struct {
+		Pin int `long:"pin" secret:"true"`
+	}{}
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"), `{"pin": "12a4"}`)
+	set, err := NewOptionSet(&data, WithConfigFile(path))
+	require.Nil(t, err)
+	err = set.Parse([]string{})
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "12a4")
+}
+
+func TestOptionSetParse_ConfigFileSecretDash(t *testing.T) {
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"), `{"token": "-"}`)
+	opts := TestConfigStruct{}
+	set, err := NewOptionSet(&opts, WithConfigFile(path), WithPromptIO(strings.NewReader("stdin\n"), &bytes.Buffer{}))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "-", opts.Token)
+}
+
+func TestOptionSetParse_ConfigFileRoundTrip(t *testing.T) {
+	opts := TestConfigStruct{Level: "warn", Port: 8080}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--db-host", "db", "-v"}))
+
+	file, err := ioutil.TempFile(t.TempDir(), "config")
+	require.Nil(t, err)
+	defer file.Close()
+	require.Nil(t, set.WriteJSON(file))
+
+	loaded := TestConfigStruct{}
+	set, err = NewOptionSet(&loaded, WithConfigFile(file.Name()))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, opts, loaded)
+}
+
+func TestParentPath(t *testing.T) {
+	require.Equal(t, "", parentPath("Database"))
+	require.Equal(t, "Database", parentPath("Database.Pool"))
+	require.Equal(t, "Database.Pool", parentPath("Database.Pool.Size"))
+}
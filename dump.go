@@ -0,0 +1,301 @@
+package opts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The value secret options are replaced with in dumps, empty secrets are not
+// replaced
+const Redacted = "********"
+
+// A node of the tree of option values written by the dump methods, keeps the
+// keys in the order the options were defined
+type dumpNode struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newDumpNode() *dumpNode {
+	return &dumpNode{values: map[string]interface{}{}}
+}
+
+// Returns the child node for the given key, creating it if needed
+func (this *dumpNode) child(key string) *dumpNode {
+	if node, ok := this.values[key].(*dumpNode); ok {
+		return node
+	}
+
+	node := newDumpNode()
+	this.set(key, node)
+	return node
+}
+
+func (this *dumpNode) set(key string, value interface{}) {
+	if _, ok := this.values[key]; !ok {
+		this.keys = append(this.keys, key)
+	}
+
+	this.values[key] = value
+}
+
+func (this *dumpNode) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString("{")
+
+	for n, key := range this.keys {
+		if n > 0 {
+			buf.WriteString(",")
+		}
+
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(this.values[key])
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// Writes this node as YAML, indented by the given prefix
+func (this *dumpNode) writeYAML(out io.Writer, indent string) error {
+	for _, key := range this.keys {
+		name, _ := json.Marshal(key)
+		value := this.values[key]
+
+		if node, ok := value.(*dumpNode); ok {
+			fmt.Fprintf(out, "%s%s:\n", indent, name)
+
+			err := node.writeYAML(out, indent+"  ")
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		items := reflect.ValueOf(value)
+
+		if items.Kind() == reflect.Slice && items.Len() > 0 {
+			fmt.Fprintf(out, "%s%s:\n", indent, name)
+
+			for n := 0; n < items.Len(); n++ {
+				scalar, err := json.Marshal(items.Index(n).Interface())
+
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintf(out, "%s  - %s\n", indent, scalar)
+			}
+
+			continue
+		}
+
+		// JSON scalars and empty lists are valid YAML
+		scalar, err := json.Marshal(value)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "%s%s: %s\n", indent, name, scalar)
+	}
+
+	return nil
+}
+
+// Returns the key of this option in dumps, the long name if there is one or
+// the field name otherwise
+func (this *Option) dumpKey() string {
+	if this.Long != "" {
+		return this.Long
+	}
+
+	path := strings.Split(this.Name, ".")
+	return path[len(path)-1]
+}
+
+// Returns true if this option is secret and has a value to hide
+func (this *Option) isRedacted() bool {
+	return this.Secret && !reflect.ValueOf(this.pointer).Elem().IsZero()
+}
+
+// Returns the current value of this option for JSON and YAML dumps. Values
+// which do not marshal to JSON natively, like durations, are formatted as
+// strings. Secret values are redacted.
+func (this *Option) dumpValue() interface{} {
+	if this.isRedacted() {
+		return Redacted
+	}
+
+	return jsonValue(reflect.ValueOf(this.pointer).Elem())
+}
+
+// Returns the given option value as it should be marshaled to JSON, slices
+// as lists of scalars
+func jsonValue(value reflect.Value) interface{} {
+	if value.Kind() == reflect.Slice && !isTextType(value.Type()) {
+		items := make([]interface{}, value.Len())
+
+		for n := range items {
+			items[n] = jsonScalar(value.Index(n))
+		}
+
+		return items
+	}
+
+	return jsonScalar(value)
+}
+
+// Returns the given value as it should be marshaled to JSON
+func jsonScalar(value reflect.Value) interface{} {
+	if isTextType(value.Type()) || value.Type() == durationType {
+		return formatValue(value)
+	}
+
+	return value.Interface()
+}
+
+// Returns the tree of current option values, nested by group
+func (this *OptionSet) dumpTree() *dumpNode {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	root := newDumpNode()
+
+	for _, opt := range this.order {
+		node := root
+
+		if opt.Group != "" {
+			for _, group := range strings.Split(opt.Group, ".") {
+				node = node.child(group)
+			}
+		}
+
+		node.set(opt.dumpKey(), opt.dumpValue())
+	}
+
+	return root
+}
+
+// Writes the current option values as a JSON object to the given io.Writer.
+// Grouped options are written as nested objects. Secret values are redacted.
+func (this *OptionSet) WriteJSON(out io.Writer) error {
+	data, err := json.MarshalIndent(this.dumpTree(), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+// Writes the current option values as a YAML mapping to the given io.Writer.
+// Grouped options are written as nested mappings. Secret values are redacted.
+func (this *OptionSet) WriteYAML(out io.Writer) error {
+	return this.dumpTree().writeYAML(out, "")
+}
+
+// Writes the current option values as KEY=value lines to the given
+// io.Writer. The keys are the environment variables of the options, or the
+// upper case field names for options without one. Positional args are left
+// out and secret values are redacted.
+func (this *OptionSet) WriteEnv(out io.Writer) error {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	for _, opt := range this.order {
+		if opt.IsPositional() {
+			continue
+		}
+
+		key := opt.Env
+
+		if key == "" {
+			key = strings.ToUpper(SnakeCase(opt.Name))
+		}
+
+		value := Redacted
+
+		if !opt.isRedacted() {
+			value = formatValue(reflect.ValueOf(opt.pointer).Elem())
+		}
+
+		if strings.ContainsAny(value, " \t\r\n\"'\\$#`") {
+			value = strconv.Quote(value)
+		}
+
+		_, err := fmt.Fprintf(out, "%s=%s\n", key, value)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the args reproducing the current option values when given to Parse.
+// Every option not at its default is given as a flag with its value (i.e.
+// "--name=foo"), using the long name if there is one, followed by "--" and the
+// positional args in order of their positional index. Options at their
+// default are left out, so parsing the args keeps their source and does not
+// warn about deprecated options. Secret values are redacted.
+func (this *OptionSet) Args() []string {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	args := []string{}
+	positionals := []string{}
+
+	for _, opt := range sortPositionals(this.order) {
+		positionals = append(positionals, opt.formattedValues()...)
+	}
+
+	for _, opt := range this.order {
+		if opt.IsPositional() || opt.Source == SourceDefault {
+			continue
+		}
+
+		names := opt.FlagNames()
+
+		if len(names) == 0 {
+			continue
+		}
+
+		name := names[0]
+
+		if opt.Long != "" {
+			name = opt.Long
+		}
+
+		raw := Redacted
+
+		if !opt.isRedacted() {
+			raw = this.flags.Lookup(name).Value.String()
+		}
+
+		args = append(args, formatFlagName(name)+"="+raw)
+	}
+
+	if len(positionals) > 0 {
+		args = append(append(args, "--"), positionals...)
+	}
+
+	return args
+}
@@ -0,0 +1,181 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type TestDumpStruct struct {
+	Name string `default:"foo" long:"name" short:"n"`
+
+	Database TestDumpDatabaseStruct `group:"database"`
+
+	Password string `env:"TEST_DUMP_PASSWORD" long:"password" secret:"true"`
+
+	Timeout time.Duration `default:"1m" long:"timeout"`
+
+	Verbose int `count:"true" short:"v"`
+
+	Address net.IP `positional:"1"`
+
+	Files []string `positional:"2"`
+}
+
+type TestDumpDatabaseStruct struct {
+	Host string `default:"localhost" long:"db-host"`
+
+	Port int `default:"5432" long:"db-port"`
+}
+
+func dumpTestParse(t *testing.T, args ...string) (*TestDumpStruct, *OptionSet) {
+	opts := TestDumpStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse(append([]string{}, args...)))
+	return &opts, set
+}
+
+func TestOptionSetWriteJSON(t *testing.T) {
+	_, set := dumpTestParse(t, "--password", "hunter2", "-vv", "10.0.0.1", "a", "b")
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSON(&buf))
+
+	expected := `{
+  "name": "foo",
+  "database": {
+    "db-host": "localhost",
+    "db-port": 5432
+  },
+  "password": "********",
+  "timeout": "1m0s",
+  "Verbose": 2,
+  "Address": "10.0.0.1",
+  "Files": [
+    "a",
+    "b"
+  ]
+}
+`
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetWriteYAML(t *testing.T) {
+	_, set := dumpTestParse(t, "--password", "hunter2", "10.0.0.1", "a", "b")
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteYAML(&buf))
+
+	expected := `"name": "foo"
+"database":
+  "db-host": "localhost"
+  "db-port": 5432
+"password": "********"
+"timeout": "1m0s"
+"Verbose": 0
+"Address": "10.0.0.1"
+"Files":
+  - "a"
+  - "b"
+`
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetWriteYAML_EmptyList(t *testing.T) {
+	_, set := dumpTestParse(t)
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteYAML(&buf))
+	require.Contains(t, buf.String(), "\"Address\": \"\"\n\"Files\": []\n")
+}
+
+func TestOptionSetWriteEnv(t *testing.T) {
+	_, set := dumpTestParse(t, "--password", "hunter2", "-n", "foo $bar")
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteEnv(&buf))
+
+	expected := `NAME="foo $bar"
+DATABASE_HOST=localhost
+DATABASE_PORT=5432
+TEST_DUMP_PASSWORD=********
+TIMEOUT=1m0s
+VERBOSE=0
+`
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetArgs(t *testing.T) {
+	_, set := dumpTestParse(t, "--password", "hunter2", "-vvv", "--db-port",
+		"6543", "--timeout", "90s", "10.0.0.1", "a", "-b")
+
+	require.Equal(t, []string{
+		"--db-port=6543",
+		"--password=********",
+		"--timeout=1m30s",
+		"-v=3",
+		"--",
+		"10.0.0.1",
+		"a",
+		"-b",
+	}, set.Args())
+}
+
+func TestOptionSetArgs_RoundTrip(t *testing.T) {
+	opts := TestNegatableOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--no-color", "--quiet"}))
+
+	copied := TestNegatableOptionSetStruct{}
+	require.Nil(t, Parse(&copied, set.Args()))
+	require.Equal(t, opts, copied)
+
+	original, set := dumpTestParse(t, "-n", "bar", "-vv", "--db-host", "db",
+		"--timeout", "5s", "10.0.0.1", "a", "-b")
+	again, _ := dumpTestParse(t, set.Args()...)
+	require.True(t, reflect.DeepEqual(original, again))
+}
+
+type TestArgsOrderStruct struct {
+	Dst string `positional:"2"`
+
+	Src string `positional:"1"`
+}
+
+func TestOptionSetArgs_Defaults(t *testing.T) {
+	opts := TestDeprecatedOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--name", "foo"}))
+	require.Equal(t, []string{"--name=foo"}, set.Args())
+
+	buf := bytes.Buffer{}
+	copied, err := NewOptionSet(&TestDeprecatedOptionSetStruct{}, WithWarningOutput(&buf))
+	require.Nil(t, err)
+	require.Nil(t, copied.Parse(set.Args()))
+	require.Equal(t, "", buf.String())
+	require.Equal(t, SourceDefault, copied.Options["Simulate"].Source)
+}
+
+func TestOptionSetArgs_PositionalOrder(t *testing.T) {
+	opts := TestArgsOrderStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"a", "b"}))
+	require.Equal(t, []string{"--", "a", "b"}, set.Args())
+
+	copied := TestArgsOrderStruct{}
+	require.Nil(t, Parse(&copied, set.Args()))
+	require.Equal(t, TestArgsOrderStruct{Dst: "b", Src: "a"}, copied)
+}
+
+func TestOptionSetWriteJSON_EmptySecret(t *testing.T) {
+	_, set := dumpTestParse(t)
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSON(&buf))
+	require.Contains(t, buf.String(), `"password": "",`)
+}
@@ -0,0 +1,109 @@
+package opts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Returned when the options of a set are defined incorrectly, i.e. when two
+// options share a flag name
+type DefinitionError struct {
+	// the problems found with the option definitions
+	Problems []string
+}
+
+func (this *DefinitionError) Error() string {
+	return "Invalid option definitions:\n  " +
+		strings.Join(this.Problems, "\n  ")
+}
+
+// Returned when a required positional arg is not given
+type MissingPositionalError struct {
+	// the option storing the missing positional arg
+	Option *Option
+}
+
+func (this *MissingPositionalError) Error() string {
+	return "Missing positional arg: " + this.Option.ArgName
+}
+
+// Returned when a required option is not given
+type MissingOptionError struct {
+	// the missing option
+	Option *Option
+}
+
+func (this *MissingOptionError) Error() string {
+	return "Missing required option: " + this.Option.displayName()
+}
+
+// Returned when more positional args are given than can be stored
+type ExtraPositionalError struct {
+	// the args that could not be stored
+	Args []string
+}
+
+func (this *ExtraPositionalError) Error() string {
+	return "Unexpected positional args: " + strings.Join(this.Args, " ")
+}
+
+// Returned when a config file cannot be read or sets invalid values
+type ConfigFileError struct {
+	// the path of the config file
+	File string
+
+	// the error that occurred
+	Err error
+}
+
+func (this *ConfigFileError) Error() string {
+	return fmt.Sprintf("Invalid config file %s: %s", this.File, this.Err)
+}
+
+// Returned when a struct tag is malformed
+type TagSyntaxError struct {
+	// the name of the field with the malformed tag, if known
+	Field string
+
+	// the message describing the error
+	Msg string
+
+	// the byte offset in the tag where the error occurred
+	Pos int
+}
+
+func (this *TagSyntaxError) Error() string {
+	if this.Field == "" {
+		return fmt.Sprintf("Invalid tag at position %d: %s", this.Pos, this.Msg)
+	}
+
+	return fmt.Sprintf(
+		"Invalid tag for field %s at position %d: %s",
+		this.Field,
+		this.Pos,
+		this.Msg)
+}
+
+// Returned when an arg file cannot be read or is malformed
+type ArgFileError struct {
+	// the path of the arg file
+	File string
+
+	// the line of the arg file the error occurred on, 0 if not known
+	Line int
+
+	// the error that occurred
+	Err error
+}
+
+func (this *ArgFileError) Error() string {
+	if this.Line == 0 {
+		return fmt.Sprintf("Invalid arg file %s: %s", this.File, this.Err)
+	}
+
+	return fmt.Sprintf(
+		"Invalid arg file %s at line %d: %s",
+		this.File,
+		this.Line,
+		this.Err)
+}
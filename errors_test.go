@@ -0,0 +1,47 @@
+package opts
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDefinitionError_Error(t *testing.T) {
+	err := &DefinitionError{Problems: []string{"foo", "bar"}}
+	require.Equal(t, "Invalid option definitions:\n  foo\n  bar", err.Error())
+}
+
+func TestMissingPositionalError_Error(t *testing.T) {
+	err := &MissingPositionalError{Option: &Option{ArgName: "SRC"}}
+	require.Equal(t, "Missing positional arg: SRC", err.Error())
+}
+
+func TestMissingOptionError_Error(t *testing.T) {
+	err := &MissingOptionError{Option: &Option{Long: "name", Short: "n"}}
+	require.Equal(t, "Missing required option: --name", err.Error())
+	err.Option = &Option{Short: "n"}
+	require.Equal(t, "Missing required option: -n", err.Error())
+}
+
+func TestExtraPositionalError_Error(t *testing.T) {
+	err := &ExtraPositionalError{Args: []string{"foo", "bar"}}
+	require.Equal(t, "Unexpected positional args: foo bar", err.Error())
+}
+
+func TestTagSyntaxError_Error(t *testing.T) {
+	err := &TagSyntaxError{Msg: "expected a key", Pos: 3}
+	require.Equal(t, "Invalid tag at position 3: expected a key", err.Error())
+	err.Field = "Name"
+	require.Equal(t,
+		"Invalid tag for field Name at position 3: expected a key",
+		err.Error())
+}
+
+func TestArgFileError_Error(t *testing.T) {
+	err := &ArgFileError{File: "build.args", Err: errors.New("File not found")}
+	require.Equal(t, "Invalid arg file build.args: File not found", err.Error())
+	err.Line = 4
+	require.Equal(t,
+		"Invalid arg file build.args at line 4: File not found",
+		err.Error())
+}
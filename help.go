@@ -0,0 +1,117 @@
+package opts
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Writes the help entry for this option to the given io.Writer, using the
+// format of the flag package. All the names of the option are listed together.
+func (this *Option) writeHelp(out io.Writer, set *flag.FlagSet) {
+	names := this.FlagNames()
+
+	if len(names) == 0 {
+		return
+	}
+
+	f := set.Lookup(names[0])
+
+	if f == nil {
+		return
+	}
+
+	b := strings.Builder{}
+
+	for n, name := range names {
+		if n > 0 {
+			b.WriteString(",")
+		}
+
+		b.WriteString(" ")
+
+		if this.Negatable && name == this.Long {
+			b.WriteString("--[no-]" + name)
+		} else {
+			b.WriteString(formatFlagName(name))
+		}
+	}
+
+	// secret flags are wrapped, name the type of the wrapped value
+	if secret, ok := f.Value.(*secretValue); ok {
+		f = &flag.Flag{Name: f.Name, Usage: f.Usage, Value: secret.Value, DefValue: f.DefValue}
+	}
+
+	kind, usage := flag.UnquoteUsage(f)
+
+	if kind != "" {
+		b.WriteString(" ")
+		b.WriteString(kind)
+	}
+
+	// same layout as the flag package, single short flags fit on one line
+	if b.Len() <= 3 {
+		b.WriteString("\t")
+	} else {
+		b.WriteString("\n    \t")
+	}
+
+	b.WriteString(strings.Replace(usage, "\n", "\n    \t", -1))
+
+	if this.Counter {
+		b.WriteString(" (can be repeated)")
+	}
+
+	// the defaults of secrets may come from the environment, never show them
+	if !isZeroFlagValue(f) && !this.Secret {
+		if kind == "string" {
+			fmt.Fprintf(&b, " (default %q)", f.DefValue)
+		} else {
+			fmt.Fprintf(&b, " (default %v)", f.DefValue)
+		}
+	}
+
+	fmt.Fprint(out, " ", b.String(), "\n")
+
+	for _, name := range this.SecretFileFlagNames() {
+		if file := set.Lookup(name); file != nil {
+			kind, usage := flag.UnquoteUsage(file)
+			fmt.Fprintf(out, "  %s %s\n    \t%s\n", formatFlagName(name), kind, usage)
+		}
+	}
+}
+
+// Formats the given flag name with dashes, one dash for single letter names
+// and two for longer ones
+func formatFlagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+
+	return "--" + name
+}
+
+// Returns true if the default of the given flag is the zero value of its type
+func isZeroFlagValue(f *flag.Flag) (zero bool) {
+	if f.DefValue == "" {
+		return true
+	}
+
+	typ := reflect.TypeOf(f.Value)
+
+	if typ.Kind() != reflect.Ptr {
+		return false
+	}
+
+	// values wrapping nil pointers may panic when formatted
+	defer func() {
+		if recover() != nil {
+			zero = false
+		}
+	}()
+
+	value := reflect.New(typ.Elem()).Interface().(flag.Value)
+	return f.DefValue == value.String()
+}
@@ -0,0 +1,61 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func helpTestEntry(t *testing.T, opt *Option) string {
+	set := optionTestNewFlagSet()
+	require.Nil(t, opt.AddToFlagSet(set))
+	buf := bytes.Buffer{}
+	opt.writeHelp(&buf, set)
+	return buf.String()
+}
+
+func TestOptionWriteHelp_Short(t *testing.T) {
+	var value bool
+	entry := helpTestEntry(t, &Option{
+		Description: "Be quiet.",
+		Short:       "q",
+		Type:        "bool",
+		pointer:     &value,
+	})
+	require.Equal(t, "  -q\tBe quiet.\n", entry)
+}
+
+func TestOptionWriteHelp_Default(t *testing.T) {
+	var value int
+	entry := helpTestEntry(t, &Option{
+		Default:     "3",
+		Description: "The number of `tries`.",
+		Long:        "retries",
+		Type:        "int",
+		pointer:     &value,
+	})
+	require.Equal(t, "  --retries tries\n    \tThe number of tries. (default 3)\n", entry)
+}
+
+func TestOptionWriteHelp_ZeroDefault(t *testing.T) {
+	var value float64
+	entry := helpTestEntry(t, &Option{
+		Default:     "0",
+		Description: "The ratio.",
+		Long:        "ratio",
+		Type:        "float64",
+		pointer:     &value,
+	})
+	require.Equal(t, "  --ratio float\n    \tThe ratio.\n", entry)
+}
+
+func TestOptionWriteHelp_NoNames(t *testing.T) {
+	buf := bytes.Buffer{}
+	(&Option{}).writeHelp(&buf, optionTestNewFlagSet())
+	require.Equal(t, "", buf.String())
+}
+
+func TestFormatFlagName(t *testing.T) {
+	require.Equal(t, "-v", formatFlagName("v"))
+	require.Equal(t, "--verbose", formatFlagName("verbose"))
+}
@@ -0,0 +1,271 @@
+package conformance
+
+import (
+	"bytes"
+	"github.com/ronelliott/go-opts"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// Creates the option set of the given options
+type constructor func(data *Options, settings ...opts.Setting) (*opts.OptionSet, error)
+
+// The ways option sets are created, compared by the conformance cases
+var constructors = map[string]constructor{
+	"reflect": func(data *Options, settings ...opts.Setting) (*opts.OptionSet, error) {
+		return opts.NewOptionSet(data, settings...)
+	},
+	"static": NewOptionsOptionSet,
+}
+
+// A conformance case, parsed by every constructor
+type conformanceCase struct {
+	// the name of the case
+	Name string
+
+	// the args given to Parse
+	Args []string
+
+	// the environment variables
+	Env map[string]string
+
+	// the files config files are read from
+	Files fstest.MapFS
+
+	// the additional settings of the set
+	Settings []opts.Setting
+
+	// the standard input secrets and prompts are read from
+	Stdin string
+}
+
+var conformanceCases = []conformanceCase{
+	{Name: "defaults", Args: []string{"src"}},
+	{Name: "flags", Args: []string{
+		"--name", "bar", "--level=warn", "-vvv", "--no-color", "-q",
+		"--timeout", "1m", "--ratio", "0.25", "--retries", "3", "--workers",
+		"8", "--limit", "1024", "--db-host", "db", "--db-port", "1",
+		"--pool-size", "2", "--region", "us-2", "src", "127.0.0.1", "80",
+		"a", "b",
+	}},
+	{Name: "aliases", Args: []string{"-T", "bar", "--title", "baz", "src"}},
+	{Name: "env", Args: []string{"src"}, Env: map[string]string{
+		"APP_NAME":    "bar",
+		"APP_RETRIES": "5",
+		"APP_TOKEN":   "hunter2",
+		"DB_HOST":     "db",
+	}},
+	{Name: "secret", Args: []string{"--token", "hunter2", "src"}},
+	{Name: "deprecated", Args: []string{"--old", "bar", "src"}},
+	{Name: "deprecated alias", Args: []string{"--title", "bar", "src"}},
+	{Name: "secret stdin", Args: []string{"--token", "-", "src"}, Stdin: "hunter2\n"},
+	{
+		Name:  "secret file",
+		Args:  []string{"--token-file", "token.txt", "src"},
+		Files: fstest.MapFS{"token.txt": {Data: []byte("hunter2\n")}},
+	},
+	{
+		Name:     "arg files",
+		Args:     []string{"@args.txt", "b"},
+		Files:    fstest.MapFS{"args.txt": {Data: []byte("--name bar\nsrc\n10.0.0.1\n8\na\n")}},
+		Settings: []opts.Setting{opts.WithArgFiles()},
+	},
+	{
+		Name:     "prompt",
+		Args:     []string{},
+		Settings: []opts.Setting{opts.WithPrompting()},
+		Stdin:    "\nsrc\n",
+	},
+	{
+		Name:     "prompt eof",
+		Args:     []string{},
+		Settings: []opts.Setting{opts.WithPrompting()},
+	},
+	{
+		Name:     "config",
+		Args:     []string{"--workers", "2", "src"},
+		Files:    fstest.MapFS{"app.json": {Data: []byte(`{"level": "debug", "workers": 16, "database": {"pool": {"pool-size": 20}}}`)}},
+		Settings: []opts.Setting{opts.WithConfigFile("app.json")},
+	},
+	{Name: "strict", Args: []string{"src"}, Settings: []opts.Setting{opts.WithStrictTags()}},
+	{Name: "negatable bools", Args: []string{"--no-color", "src"}, Settings: []opts.Setting{opts.WithNegatableBools()}},
+	{Name: "invalid choice", Args: []string{"--level", "trace", "src"}},
+	{Name: "below min", Args: []string{"--workers", "0", "src"}},
+	{Name: "above max", Args: []string{"--ratio", "1.5", "src"}},
+	{Name: "pattern mismatch", Args: []string{"--region", "EU", "src"}},
+	{Name: "positional below min", Args: []string{"src", "10.0.0.1", "0"}},
+	{Name: "positional out of range", Args: []string{"src", "10.0.0.1", "300"}},
+	{Name: "invalid flag value", Args: []string{"--workers", "many", "src"}},
+	{Name: "invalid secret value", Args: []string{"--token", "hunter2", "--retries", "hunter2", "src"}},
+	{Name: "invalid positional", Args: []string{"src", "localhost"}},
+	{Name: "unknown flag", Args: []string{"--nope", "src"}},
+	{Name: "missing positional", Args: []string{}},
+	{Name: "flags after positionals", Args: []string{"src", "10.0.0.1", "8", "a", "--", "-b"}},
+	{Name: "invalid env", Args: []string{"src"}, Env: map[string]string{"APP_RETRIES": "many"}},
+}
+
+// The observable state of an option set after a parse
+type conformanceResult struct {
+	Err      string
+	Data     Options
+	Options  []map[string]interface{}
+	Usage    string
+	Help     string
+	Args     []string
+	JSON     string
+	Prompts  string
+	Warnings string
+}
+
+// Parses the given case with an option set created by the given constructor
+func runCase(t *testing.T, create constructor, test conformanceCase) conformanceResult {
+	result := conformanceResult{}
+	prompts := bytes.Buffer{}
+	warnings := bytes.Buffer{}
+	env := func(name string) (string, bool) {
+		value, ok := test.Env[name]
+		return value, ok
+	}
+
+	settings := append([]opts.Setting{
+		opts.WithEnv(env),
+		opts.WithFS(test.Files),
+		opts.WithOutput(&bytes.Buffer{}, &warnings),
+		opts.WithPromptIO(strings.NewReader(test.Stdin), &prompts),
+	}, test.Settings...)
+
+	set, err := create(&result.Data, settings...)
+
+	if err == nil {
+		err = set.Parse(test.Args)
+	}
+
+	if err != nil {
+		result.Err = err.Error()
+	}
+
+	if set != nil {
+		help := bytes.Buffer{}
+		json := bytes.Buffer{}
+		set.WriteHelp(&help)
+		require.Nil(t, set.WriteJSON(&json))
+
+		result.Options = describe(set)
+		result.Usage = set.Usage()
+		result.Help = help.String()
+		result.Args = set.Args()
+		result.JSON = json.String()
+	}
+
+	result.Prompts = prompts.String()
+	result.Warnings = warnings.String()
+	return result
+}
+
+// Returns the exported fields and values of the options of the given set
+func describe(set *opts.OptionSet) []map[string]interface{} {
+	options := []map[string]interface{}{}
+
+	for _, opt := range set.List() {
+		value := reflect.ValueOf(opt).Elem()
+		fields := map[string]interface{}{"Value": opt.Value()}
+
+		for n := 0; n < value.NumField(); n++ {
+			if field := value.Type().Field(n); field.IsExported() {
+				fields[field.Name] = value.Field(n).Interface()
+			}
+		}
+
+		options = append(options, fields)
+	}
+
+	return options
+}
+
+func TestConformance(t *testing.T) {
+	for _, test := range conformanceCases {
+		test := test
+
+		t.Run(test.Name, func(t *testing.T) {
+			want := runCase(t, constructors["reflect"], test)
+			got := runCase(t, constructors["static"], test)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestConformance_Reparse(t *testing.T) {
+	sets := map[string]*opts.OptionSet{}
+	data := map[string]*Options{}
+
+	for name, create := range constructors {
+		data[name] = &Options{}
+		set, err := create(data[name])
+		require.Nil(t, err)
+		sets[name] = set
+	}
+
+	for _, args := range [][]string{{"--name", "bar", "-vv", "src", "10.0.0.1", "8", "a"}, {"src"}} {
+		for name, set := range sets {
+			require.Nil(t, set.Parse(args), name)
+		}
+
+		require.Equal(t, data["reflect"], data["static"])
+		require.Equal(t, describe(sets["reflect"]), describe(sets["static"]))
+	}
+
+	for name, set := range sets {
+		require.Nil(t, set.Set("pool-size", "7"), name)
+		set.Reset()
+	}
+
+	require.Equal(t, data["reflect"], data["static"])
+}
+
+// The args parsed by the benchmarks
+var benchmarkArgs = []string{"--name", "bar", "-vv", "--db-host", "db", "src", "10.0.0.1", "8", "a"}
+
+// Benchmarks creating option sets. The struct is only walked by the first
+// set created by reflection, later sets use the cached fields. The first set
+// of a process, which is what short lived commands pay for, is benchmarked by
+// BenchmarkNewOptionSet/uncached of the opts package.
+func BenchmarkNewOptionSet(b *testing.B) {
+	for _, name := range []string{"reflect", "static"} {
+		create := constructors[name]
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for n := 0; n < b.N; n++ {
+				if _, err := create(&Options{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, name := range []string{"reflect", "static"} {
+		create := constructors[name]
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for n := 0; n < b.N; n++ {
+				set, err := create(&Options{})
+
+				if err == nil {
+					err = set.Parse(benchmarkArgs)
+				}
+
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package conformance checks option sets created by parsers generated with
+// "optsgen --parser" behave the same as option sets created by reflection.
+// Its tests run the same cases against both and compare the results, and its
+// benchmarks compare the cost of both.
+package conformance
+
+import (
+	"net"
+	"time"
+)
+
+//go:generate go run ../../cmd/optsgen --parser --type Options --out options_parser.go
+
+// An options struct using every kind of option
+type Options struct {
+	Name string `json:"name" long:"name" short:"n" aliases:"title,-T" deprecated:"use --name instead" deprecated-aliases:"title" default:"foo" env:"APP_NAME" description:"The name to use."`
+
+	Level string `long:"level" choices:"debug,info,warn" default:"info" description:"The log level."`
+
+	Verbose int `short:"v" count:"true" description:"Log more, repeat for even more."`
+
+	Color bool `long:"color" negatable:"true" default:"true" description:"Color the output."`
+
+	Quiet bool `short:"q" description:"Log nothing."`
+
+	Timeout time.Duration `long:"timeout" default:"30s" reloadable:"false" description:"The timeout of requests."`
+
+	Ratio float64 `long:"ratio" default:"0.5" min:"0" max:"1" description:"The sampling ratio."`
+
+	Retries int64 `long:"retries" env:"APP_RETRIES" description:"The number of retries."`
+
+	Workers uint `long:"workers" default:"4" min:"1" max:"64" description:"The number of workers."`
+
+	Limit uint64 `long:"limit" hidden:"true" description:"The maximum request size."`
+
+	Token string `long:"token" secret:"true" env:"APP_TOKEN" description:"The API token."`
+
+	Old string `long:"old" deprecated:"use --name instead" description:"The old name."`
+
+	Region string `long:"region" pattern:"^[a-z]+-[0-9]+$" default:"eu-1" description:"The region."`
+
+	Database DatabaseOptions `group:"database"`
+
+	Source string `positional:"1" name:"SRC" required:"true" description:"The source."`
+
+	Addr net.IP `positional:"2" name:"ADDR" description:"The address."`
+
+	Port int8 `positional:"3" name:"PORT" min:"1" description:"The port."`
+
+	Files []string `positional:"true" name:"FILES" description:"The files."`
+
+	Internal string
+}
+
+// The options of the database, a group nested in Options
+type DatabaseOptions struct {
+	Host string `long:"db-host" default:"localhost" env:"DB_HOST" description:"The database host."`
+
+	Port int `long:"db-port" default:"5432" description:"The database port."`
+
+	Pool PoolOptions `group:"pool"`
+}
+
+// The options of the database connection pool, a group nested in a group
+type PoolOptions struct {
+	Size int `long:"pool-size" default:"10" description:"The size of the pool."`
+}
@@ -0,0 +1,212 @@
+// Code generated by optsgen --parser from Options. DO NOT EDIT.
+
+package conformance
+
+import (
+	"github.com/ronelliott/go-opts"
+	"reflect"
+)
+
+// The options of Options, as read from the tags of its fields
+var optionsOptions = []opts.Option{
+	{
+		Aliases:           []string{"title", "T"},
+		ArgName:           "NAME",
+		Default:           "foo",
+		Deprecated:        "use --name instead",
+		DeprecatedAliases: []string{"title"},
+		Description:       "The name to use.",
+		Env:               "APP_NAME",
+		Long:              "name",
+		Name:              "Name",
+		Short:             "n",
+		Tags:              opts.TagSet{"aliases": "title,-T", "default": "foo", "deprecated": "use --name instead", "deprecated-aliases": "title", "description": "The name to use.", "env": "APP_NAME", "json": "name", "long": "name", "short": "n"},
+	},
+	{
+		ArgName:     "LEVEL",
+		Choices:     []string{"debug", "info", "warn"},
+		Default:     "info",
+		Description: "The log level.",
+		Long:        "level",
+		Name:        "Level",
+		Tags:        opts.TagSet{"choices": "debug,info,warn", "default": "info", "description": "The log level.", "long": "level"},
+	},
+	{
+		ArgName:     "VERBOSE",
+		Counter:     true,
+		Description: "Log more, repeat for even more.",
+		Name:        "Verbose",
+		Short:       "v",
+		Tags:        opts.TagSet{"count": "true", "description": "Log more, repeat for even more.", "short": "v"},
+	},
+	{
+		ArgName:     "COLOR",
+		Default:     "true",
+		Description: "Color the output.",
+		Long:        "color",
+		Name:        "Color",
+		Negatable:   true,
+		Tags:        opts.TagSet{"default": "true", "description": "Color the output.", "long": "color", "negatable": "true"},
+	},
+	{
+		ArgName:     "QUIET",
+		Description: "Log nothing.",
+		Name:        "Quiet",
+		Short:       "q",
+		Tags:        opts.TagSet{"description": "Log nothing.", "short": "q"},
+	},
+	{
+		ArgName:     "TIMEOUT",
+		Default:     "30s",
+		Description: "The timeout of requests.",
+		Long:        "timeout",
+		Name:        "Timeout",
+		Tags:        opts.TagSet{"default": "30s", "description": "The timeout of requests.", "long": "timeout", "reloadable": "false"},
+	},
+	{
+		ArgName:     "RATIO",
+		Default:     "0.5",
+		Description: "The sampling ratio.",
+		Long:        "ratio",
+		Max:         "1",
+		Min:         "0",
+		Name:        "Ratio",
+		Tags:        opts.TagSet{"default": "0.5", "description": "The sampling ratio.", "long": "ratio", "max": "1", "min": "0"},
+	},
+	{
+		ArgName:     "RETRIES",
+		Description: "The number of retries.",
+		Env:         "APP_RETRIES",
+		Long:        "retries",
+		Name:        "Retries",
+		Tags:        opts.TagSet{"description": "The number of retries.", "env": "APP_RETRIES", "long": "retries"},
+	},
+	{
+		ArgName:     "WORKERS",
+		Default:     "4",
+		Description: "The number of workers.",
+		Long:        "workers",
+		Max:         "64",
+		Min:         "1",
+		Name:        "Workers",
+		Tags:        opts.TagSet{"default": "4", "description": "The number of workers.", "long": "workers", "max": "64", "min": "1"},
+	},
+	{
+		ArgName:     "LIMIT",
+		Description: "The maximum request size.",
+		Hidden:      true,
+		Long:        "limit",
+		Name:        "Limit",
+		Tags:        opts.TagSet{"description": "The maximum request size.", "hidden": "true", "long": "limit"},
+	},
+	{
+		ArgName:     "TOKEN",
+		Description: "The API token.",
+		Env:         "APP_TOKEN",
+		Long:        "token",
+		Name:        "Token",
+		Secret:      true,
+		Tags:        opts.TagSet{"description": "The API token.", "env": "APP_TOKEN", "long": "token", "secret": "true"},
+	},
+	{
+		ArgName:     "OLD",
+		Deprecated:  "use --name instead",
+		Description: "The old name.",
+		Long:        "old",
+		Name:        "Old",
+		Tags:        opts.TagSet{"deprecated": "use --name instead", "description": "The old name.", "long": "old"},
+	},
+	{
+		ArgName:     "REGION",
+		Default:     "eu-1",
+		Description: "The region.",
+		Long:        "region",
+		Name:        "Region",
+		Pattern:     "^[a-z]+-[0-9]+$",
+		Tags:        opts.TagSet{"default": "eu-1", "description": "The region.", "long": "region", "pattern": "^[a-z]+-[0-9]+$"},
+	},
+	{
+		ArgName:     "HOST",
+		Default:     "localhost",
+		Description: "The database host.",
+		Env:         "DB_HOST",
+		Group:       "database",
+		Long:        "db-host",
+		Name:        "Database.Host",
+		Tags:        opts.TagSet{"default": "localhost", "description": "The database host.", "env": "DB_HOST", "long": "db-host"},
+	},
+	{
+		ArgName:     "PORT",
+		Default:     "5432",
+		Description: "The database port.",
+		Group:       "database",
+		Long:        "db-port",
+		Name:        "Database.Port",
+		Tags:        opts.TagSet{"default": "5432", "description": "The database port.", "long": "db-port"},
+	},
+	{
+		ArgName:     "SIZE",
+		Default:     "10",
+		Description: "The size of the pool.",
+		Group:       "database.pool",
+		Long:        "pool-size",
+		Name:        "Database.Pool.Size",
+		Tags:        opts.TagSet{"default": "10", "description": "The size of the pool.", "long": "pool-size"},
+	},
+	{
+		ArgName:     "SRC",
+		Description: "The source.",
+		Name:        "Source",
+		Position:    1,
+		Required:    true,
+		Tags:        opts.TagSet{"description": "The source.", "name": "SRC", "positional": "1", "required": "true"},
+	},
+	{
+		ArgName:     "ADDR",
+		Description: "The address.",
+		Name:        "Addr",
+		Position:    2,
+		Tags:        opts.TagSet{"description": "The address.", "name": "ADDR", "positional": "2"},
+	},
+	{
+		ArgName:     "PORT",
+		Description: "The port.",
+		Min:         "1",
+		Name:        "Port",
+		Position:    3,
+		Tags:        opts.TagSet{"description": "The port.", "min": "1", "name": "PORT", "positional": "3"},
+	},
+	{
+		ArgName:     "FILES",
+		Description: "The files.",
+		Name:        "Files",
+		Tags:        opts.TagSet{"description": "The files.", "name": "FILES", "positional": "true"},
+	},
+}
+
+// Creates the OptionSet of the given Options, like opts.NewOptionSet but
+// without reflecting on the struct or parsing its tags
+func NewOptionsOptionSet(data *Options, settings ...opts.Setting) (*opts.OptionSet, error) {
+	return opts.NewStaticOptionSet("Options", optionsOptions, []opts.Binding{
+		opts.Bind(&data.Name),
+		opts.Bind(&data.Level),
+		opts.Bind(&data.Verbose),
+		opts.Bind(&data.Color),
+		opts.Bind(&data.Quiet),
+		opts.Bind(&data.Timeout),
+		opts.Bind(&data.Ratio),
+		opts.Bind(&data.Retries),
+		opts.Bind(&data.Workers),
+		opts.Bind(&data.Limit),
+		opts.Bind(&data.Token),
+		opts.Bind(&data.Old),
+		opts.Bind(&data.Region),
+		opts.Bind(&data.Database.Host),
+		opts.Bind(&data.Database.Port),
+		opts.Bind(&data.Database.Pool.Size),
+		opts.Bind(&data.Source),
+		opts.BindText(&data.Addr, "net.IP", reflect.Slice),
+		opts.Bind(&data.Port),
+		opts.Bind(&data.Files),
+	}, settings...)
+}
@@ -0,0 +1,288 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Checks the limits of this option, its min, max and pattern, are valid for
+// the given type of its field. Compiles the pattern.
+func (this *Option) checkLimitDefinitions(typ reflect.Type) error {
+	typ = elemType(typ)
+
+	if this.Min != "" || this.Max != "" {
+		if !isNumberType(typ) {
+			return errors.New(fmt.Sprintf(
+				"Invalid option %s: min and max require a number type, not %s",
+				this.Name,
+				this.Type))
+		}
+
+		for _, limit := range []string{this.Min, this.Max} {
+			if _, err := parseLimit(typ, limit); limit != "" && err != nil {
+				return errors.New(fmt.Sprintf(
+					"Invalid limit for option %s: %s",
+					this.Name,
+					limit))
+			}
+		}
+
+		if this.Min != "" && this.Max != "" {
+			min, _ := parseLimit(typ, this.Min)
+			max, _ := parseLimit(typ, this.Max)
+
+			if compareNumbers(min, max) > 0 {
+				return errors.New(fmt.Sprintf(
+					"Invalid option %s: min is greater than max",
+					this.Name))
+			}
+		}
+	}
+
+	if this.Pattern == "" {
+		return nil
+	}
+
+	if typ.Kind() != reflect.String || isTextType(typ) {
+		return errors.New(fmt.Sprintf(
+			"Invalid option %s: pattern requires a string type, not %s",
+			this.Name,
+			this.Type))
+	}
+
+	return this.compilePattern()
+}
+
+// Compiles the pattern of this option, if it has one. Compiled patterns are
+// cached, as static sets compile them every time they are created.
+func (this *Option) compilePattern() error {
+	if this.Pattern == "" {
+		return nil
+	}
+
+	if cached, ok := patternCache.Load(this.Pattern); ok {
+		this.pattern = cached.(*regexp.Regexp)
+		return nil
+	}
+
+	pattern, err := regexp.Compile(this.Pattern)
+
+	if err != nil {
+		return errors.New(fmt.Sprintf(
+			"Invalid pattern for option %s: %s",
+			this.Name,
+			err.Error()))
+	}
+
+	patternCache.Store(this.Pattern, pattern)
+	this.pattern = pattern
+	return nil
+}
+
+// Checks the given value of this option is within its limits: at least its
+// min and at most its max for numbers, matching its pattern for strings. The
+// elements of slices are checked one by one. Secret values are left out of
+// the error.
+func (this *Option) checkLimits(value reflect.Value) error {
+	value, problem := this.limitProblem(value)
+
+	if problem == "" {
+		return nil
+	}
+
+	return this.limitError(formatValue(value), problem)
+}
+
+// Creates the error returned when the given formatted value of this option is
+// not within its limits. Secret values are left out of the error.
+func (this *Option) limitError(value, problem string) error {
+	if this.Secret {
+		return errors.New(fmt.Sprintf(
+			"Invalid value for %s, %s",
+			this.displayName(),
+			problem))
+	}
+
+	return errors.New(fmt.Sprintf(
+		"Invalid value '%s' for %s, %s",
+		value,
+		this.displayName(),
+		problem))
+}
+
+// Returns the first value of the given value of this option which is not
+// within its limits, and how it is not (i.e. "expected at least 1"). Returns
+// an empty problem if the value is within the limits.
+func (this *Option) limitProblem(value reflect.Value) (reflect.Value, string) {
+	if this.Min == "" && this.Max == "" && this.pattern == nil {
+		return value, ""
+	}
+
+	if value.Kind() == reflect.Slice && !isTextType(value.Type()) {
+		for n := 0; n < value.Len(); n++ {
+			if item, problem := this.limitProblem(value.Index(n)); problem != "" {
+				return item, problem
+			}
+		}
+
+		return value, ""
+	}
+
+	if this.pattern != nil {
+		if !this.pattern.MatchString(value.String()) {
+			return value, "expected a value matching " + this.Pattern
+		}
+
+		return value, ""
+	}
+
+	if this.Min != "" {
+		if min, _ := parseLimit(value.Type(), this.Min); compareNumbers(value, min) < 0 {
+			return value, "expected at least " + this.Min
+		}
+	}
+
+	if this.Max != "" {
+		if max, _ := parseLimit(value.Type(), this.Max); compareNumbers(value, max) > 0 {
+			return value, "expected at most " + this.Max
+		}
+	}
+
+	return value, ""
+}
+
+// Checks the given raw answer to a prompt is within the limits of this
+// option, before it is stored. Answers which cannot be parsed are left to be
+// reported when they are stored.
+func (this *Option) checkAnswerLimits(answer string) error {
+	if this.Min == "" && this.Max == "" && this.pattern == nil {
+		return nil
+	}
+
+	if this.binding != nil {
+		formatted, err := this.binding.parse(answer)
+
+		if err != nil {
+			return nil
+		}
+
+		if problem := this.boundLimitProblem(formatted); problem != "" {
+			return errors.New(strings.ToUpper(problem[:1]) + problem[1:])
+		}
+
+		return nil
+	}
+
+	value := reflect.New(reflect.TypeOf(this.pointer).Elem())
+
+	if setValue(value.Interface(), answer) != nil {
+		return nil
+	}
+
+	if _, problem := this.limitProblem(value.Elem()); problem != "" {
+		return errors.New(strings.ToUpper(problem[:1]) + problem[1:])
+	}
+
+	return nil
+}
+
+// Parses the given min or max limit of numbers of the given type. Limits of
+// integer types must be integers, so they are compared without rounding.
+func parseLimit(typ reflect.Type, raw string) (reflect.Value, error) {
+	switch {
+	case isIntKind(typ.Kind()):
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		return reflect.ValueOf(limit), err
+
+	case isUintKind(typ.Kind()):
+		limit, err := strconv.ParseUint(raw, 10, 64)
+		return reflect.ValueOf(limit), err
+	}
+
+	limit, err := strconv.ParseFloat(raw, 64)
+
+	if err == nil && (math.IsInf(limit, 0) || math.IsNaN(limit)) {
+		err = errors.New("Limit is not a finite number")
+	}
+
+	return reflect.ValueOf(limit), err
+}
+
+// Compares the given numbers, which must both be signed integers, unsigned
+// integers or floats. Returns -1 if a is less than b, 1 if a is greater than
+// b and 0 if they are equal.
+func compareNumbers(a, b reflect.Value) int {
+	switch {
+	case isIntKind(a.Kind()) && a.Int() != b.Int():
+		return compareOrder(a.Int() < b.Int())
+	case isUintKind(a.Kind()) && a.Uint() != b.Uint():
+		return compareOrder(a.Uint() < b.Uint())
+	case a.Kind() == reflect.Float32 || a.Kind() == reflect.Float64:
+		if a.Float() != b.Float() {
+			return compareOrder(a.Float() < b.Float())
+		}
+	}
+
+	return 0
+}
+
+// Returns -1 if less is true, 1 otherwise
+func compareOrder(less bool) int {
+	if less {
+		return -1
+	}
+
+	return 1
+}
+
+// Returns the type of the elements of the given type for slices, the type
+// itself otherwise
+func elemType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Slice && !isTextType(typ) {
+		return typ.Elem()
+	}
+
+	return typ
+}
+
+// Returns true if the given type is a number, durations are not
+func isNumberType(typ reflect.Type) bool {
+	if typ == durationType || isTextType(typ) {
+		return false
+	}
+
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8,
+		reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// Returns true if the given kind is a signed integer
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+
+	return false
+}
+
+// Returns true if the given kind is an unsigned integer
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package opts
+
+import (
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type TestLimitsStruct struct {
+	Port    int     `long:"port" min:"1" max:"65535" default:"80"`
+	Ratio   float64 `long:"ratio" min:"0" max:"1"`
+	Workers uint    `long:"workers" max:"16"`
+	Name    string  `long:"name" pattern:"^[a-z]+$"`
+	Token   string  `long:"token" pattern:"^tk_" secret:"true"`
+	Host    string  `positional:"1" pattern:"^[a-z]"`
+	Ports   []int   `positional:"2" min:"1"`
+}
+
+type TestLimitsStringStruct struct {
+	Name string `long:"name" min:"1"`
+}
+
+type TestLimitsNumberStruct struct {
+	Port int `long:"port" pattern:"^1"`
+}
+
+type TestLimitsInvalidStruct struct {
+	Port int `long:"port" min:"one"`
+}
+
+type TestLimitsReversedStruct struct {
+	Port int `long:"port" min:"10" max:"1"`
+}
+
+type TestLimitsFractionStruct struct {
+	Port int `long:"port" max:"1.5"`
+}
+
+type TestLimitsNegativeStruct struct {
+	Workers uint `long:"workers" min:"-1"`
+}
+
+type TestLimitsLargeStruct struct {
+	Count int64  `long:"count" max:"9007199254740993"`
+	Size  uint64 `long:"size" min:"18446744073709551615"`
+}
+
+type TestLimitsPatternStruct struct {
+	Name string `long:"name" pattern:"(["`
+}
+
+func TestOptionSetParse_Limits(t *testing.T) {
+	opts := TestLimitsStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--port", "443", "--ratio", "0.5", "--name", "app", "--token", "tk_1", "host", "1", "2"}))
+	require.Equal(t, 443, opts.Port)
+	require.Equal(t, []int{1, 2}, opts.Ports)
+
+	tests := map[string][]string{
+		"Invalid value '0' for --port, expected at least 1":                  {"--port", "0"},
+		"Invalid value '70000' for --port, expected at most 65535":           {"--port", "70000"},
+		"Invalid value '1.5' for --ratio, expected at most 1":                {"--ratio", "1.5"},
+		"Invalid value '17' for --workers, expected at most 16":              {"--workers", "17"},
+		"Invalid value 'App' for --name, expected a value matching ^[a-z]+$": {"--name", "App"},
+		"Invalid value for --token, expected a value matching ^tk_":          {"--token", "secret"},
+		"Invalid value '0' for PORTS, expected at least 1":                   {"host", "1", "0"},
+		"Invalid value 'Host' for HOST, expected a value matching ^[a-z]":    {"Host"},
+	}
+
+	for msg, args := range tests {
+		err = set.Parse(args)
+		require.NotNil(t, err, msg)
+		require.Equal(t, msg, err.Error())
+	}
+}
+
+func TestOptionSetParse_LimitsLarge(t *testing.T) {
+	opts := TestLimitsLargeStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--count", "9007199254740993", "--size", "18446744073709551615"}))
+	require.Equal(t, int64(9007199254740993), opts.Count)
+
+	tests := map[string][]string{
+		"Invalid value '9007199254740994' for --count, expected at most 9007199254740993":         {"--count", "9007199254740994"},
+		"Invalid value '18446744073709551614' for --size, expected at least 18446744073709551615": {"--size", "18446744073709551614"},
+	}
+
+	for msg, args := range tests {
+		err = set.Parse(args)
+		require.NotNil(t, err, msg)
+		require.Equal(t, msg, err.Error())
+	}
+}
+
+func TestOptionSetParse_LimitsDefaults(t *testing.T) {
+	// defaults are not checked, only given values
+	opts := TestLimitsStruct{Port: 0}
+	set, err := NewOptionSet(&opts, WithEnv(func(name string) (string, bool) { return "", false }))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, 80, opts.Port)
+}
+
+func TestOptionSetSet_Limits(t *testing.T) {
+	opts := TestLimitsStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+
+	require.Equal(t, "Invalid value '0' for --port, expected at least 1", set.Set("port", "0").Error())
+	require.Equal(t, 80, opts.Port)
+	require.Nil(t, set.Set("port", "8080"))
+	require.Equal(t, 8080, opts.Port)
+}
+
+func TestOptionSetParse_LimitsConfig(t *testing.T) {
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"), `{"port": 0}`)
+	set, err := NewOptionSet(&TestLimitsStruct{}, WithConfigFile(path))
+	require.Nil(t, err)
+	require.Equal(t, "Invalid value '0' for --port, expected at least 1", set.Parse([]string{}).Error())
+}
+
+func TestOptionSetParse_LimitsPrompt(t *testing.T) {
+	opts := TestPromptLimitsStruct{}
+	set, out := promptTestNewSet(t, &opts, "0\n8080\n")
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, 8080, opts.Port)
+	require.Equal(t, "--port: Invalid value: Expected at least 1\n--port: ", out.String())
+}
+
+type TestPromptLimitsStruct struct {
+	Port int `long:"port" required:"true" min:"1"`
+}
+
+func TestNewOptionSet_LimitDefinitions(t *testing.T) {
+	tests := map[string]interface{}{
+		"Invalid option Name: min and max require a number type, not string":            &TestLimitsStringStruct{},
+		"Invalid option Port: pattern requires a string type, not int":                  &TestLimitsNumberStruct{},
+		"Invalid limit for option Port: one":                                            &TestLimitsInvalidStruct{},
+		"Invalid limit for option Port: 1.5":                                            &TestLimitsFractionStruct{},
+		"Invalid limit for option Workers: -1":                                          &TestLimitsNegativeStruct{},
+		"Invalid option Port: min is greater than max":                                  &TestLimitsReversedStruct{},
+		"Invalid pattern for option Name: error parsing regexp: missing closing ]: `[`": &TestLimitsPatternStruct{},
+	}
+
+	for msg, data := range tests {
+		_, err := NewOptionSet(data)
+		require.NotNil(t, err, msg)
+		require.Equal(t, msg, err.Error())
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := parseLimit(reflect.TypeOf(0.0), "-1.5")
+	require.Nil(t, err)
+	require.Equal(t, -1.5, limit.Interface())
+
+	limit, err = parseLimit(reflect.TypeOf(int8(0)), "9007199254740993")
+	require.Nil(t, err)
+	require.Equal(t, int64(9007199254740993), limit.Interface())
+
+	limit, err = parseLimit(reflect.TypeOf(uint(0)), "18446744073709551615")
+	require.Nil(t, err)
+	require.Equal(t, uint64(18446744073709551615), limit.Interface())
+
+	_, err = parseLimit(reflect.TypeOf(0.0), "Inf")
+	require.NotNil(t, err)
+
+	_, err = parseLimit(reflect.TypeOf(0), "1.5")
+	require.NotNil(t, err)
+}
@@ -0,0 +1,74 @@
+package opts
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A NamingStrategy derives the long flag name of an option from the name of
+// its field
+type NamingStrategy func(field string) string
+
+// Derives kebab-case names, i.e. "MaxConns" becomes "max-conns"
+func KebabCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "-"))
+}
+
+// Derives snake_case names, i.e. "MaxConns" becomes "max_conns"
+func SnakeCase(field string) string {
+	return strings.ToLower(strings.Join(splitWords(field), "_"))
+}
+
+// Derives lowerCamel names, i.e. "MaxConns" becomes "maxConns"
+func LowerCamelCase(field string) string {
+	words := splitWords(field)
+
+	for n, word := range words {
+		word = strings.ToLower(word)
+
+		if n > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+
+		words[n] = word
+	}
+
+	return strings.Join(words, "")
+}
+
+// Splits the given field name into words. Words start at upper case letters,
+// runs of upper case letters are kept together as acronyms (i.e. "HTTPServer"
+// becomes "HTTP" and "Server") and digits belong to the preceding word.
+// Underscores, dashes and dots separate words.
+func splitWords(field string) []string {
+	runes := []rune(field)
+	words := []string{}
+	start := 0
+
+	for n := 1; n < len(runes); n++ {
+		prev, cur := runes[n-1], runes[n]
+		lowerToUpper := !unicode.IsUpper(prev) && unicode.IsUpper(cur)
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) &&
+			n+1 < len(runes) && unicode.IsLower(runes[n+1])
+
+		if cur == '_' || cur == '-' || cur == '.' {
+			if n > start {
+				words = append(words, string(runes[start:n]))
+			}
+
+			start = n + 1
+			continue
+		}
+
+		if (lowerToUpper || acronymEnd) && n > start {
+			words = append(words, string(runes[start:n]))
+			start = n
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
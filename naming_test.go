@@ -0,0 +1,36 @@
+package opts
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestKebabCase(t *testing.T) {
+	require.Equal(t, "max-conns", KebabCase("MaxConns"))
+	require.Equal(t, "http-server", KebabCase("HTTPServer"))
+	require.Equal(t, "id", KebabCase("ID"))
+	require.Equal(t, "user-id", KebabCase("UserID"))
+	require.Equal(t, "use2-fa", KebabCase("Use2FA"))
+	require.Equal(t, "dry-run", KebabCase("Dry_Run"))
+	require.Equal(t, "name", KebabCase("Name"))
+	require.Equal(t, "database-host", KebabCase("Database.Host"))
+}
+
+func TestSnakeCase(t *testing.T) {
+	require.Equal(t, "max_conns", SnakeCase("MaxConns"))
+	require.Equal(t, "http_server", SnakeCase("HTTPServer"))
+}
+
+func TestLowerCamelCase(t *testing.T) {
+	require.Equal(t, "maxConns", LowerCamelCase("MaxConns"))
+	require.Equal(t, "httpServer", LowerCamelCase("HTTPServer"))
+	require.Equal(t, "id", LowerCamelCase("ID"))
+	require.Equal(t, "", LowerCamelCase(""))
+}
+
+func TestSplitWords(t *testing.T) {
+	require.Equal(t, []string{"Max", "Conns"}, splitWords("MaxConns"))
+	require.Equal(t, []string{"HTTP", "Server"}, splitWords("HTTPServer"))
+	require.Equal(t, []string{"Dry", "Run"}, splitWords("Dry__Run"))
+	require.Equal(t, []string{}, splitWords(""))
+}
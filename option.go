@@ -6,34 +6,143 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// Where the current value of an option came from
+type Source string
+
+const (
+	// the value is the default of the option
+	SourceDefault Source = "default"
+
+	// the value was read from the environment variable of the option
+	SourceEnv Source = "env"
+
+	// the value was read from a config file
+	SourceConfig Source = "config"
+
+	// the value was given as a flag
+	SourceFlag Source = "flag"
+
+	// the value was given as a positional arg
+	SourcePositional Source = "positional"
+
+	// the value was entered when prompted for
+	SourcePrompt Source = "prompt"
+
+	// the value was set with OptionSet.Set
+	SourceSet Source = "set"
 )
 
 type Option struct {
+	// additional names the option can be set with (i.e. "dry-run")
+	Aliases []string
+
+	// the name of the positional arg shown in usage lines (i.e. "SRC")
+	ArgName string
+
+	// the values the option can be set to, any value if empty
+	Choices []string
+
+	// true if each use of the option increments the value (i.e. "-vvv")
+	Counter bool
+
 	// the default value for the option
 	Default string
 
 	// the short description of the option
 	Description string
 
+	// the deprecation notice for the option, empty if not deprecated
+	Deprecated string
+
+	// the aliases which are deprecated (i.e. "dry-run"), all names of a
+	// deprecated option are if empty
+	DeprecatedAliases []string
+
+	// the environment variable the default is read from
+	Env string
+
+	// the group of the option, the path of the nested struct the field is in
+	// (i.e. "Database"), empty for top level fields
+	Group string
+
 	// the help for the option
 	Help string
 
+	// true if the option should be left out of the help output
+	Hidden bool
+
+	// the kind of the option value
+	Kind reflect.Kind
+
 	// the short tag for the field (i.e. "--verbose")
 	Long string
 
+	// the largest value of a number option, no limit if empty
+	Max string
+
+	// the smallest value of a number option, no limit if empty
+	Min string
+
 	// the name of the field
 	Name string
 
+	// true if a bool option can be turned off with "--no-<long>"
+	Negatable bool
+
+	// the regular expression the value of a string option must match, any
+	// value if empty
+	Pattern string
+
+	// the index of the positional arg, starting at 1. 0 for options and for
+	// positional options storing all leftover args.
+	Position int
+
+	// true if the option or positional arg must be given
+	Required bool
+
+	// true if the value should not be shown, i.e. for passwords
+	Secret bool
+
 	// the short tag for the field (i.e. "-v")
 	Short string
 
+	// where the current value of the option came from
+	Source Source
+
 	// the tags for the field
 	Tags TagSet
 
 	// the type of the option
 	Type string
 
+	// the binding of the option to its field, nil for options bound by
+	// reflection
+	binding Binding
+
+	// the default given by the tags or the field value, without the value of
+	// the environment variable of the option. Restored by reloads once the
+	// variable is unset.
+	baseDefault reflect.Value
+
+	// the source of the default value, restored by OptionSet.Reset
+	defaultSource Source
+
+	// the default value, restored by OptionSet.Reset
+	defaultValue reflect.Value
+
+	// the index of the field in the options struct, nil for options added
+	// with OptionSet.AddOption
+	index []int
+
+	// the compiled Pattern, nil if there is none
+	pattern *regexp.Regexp
+
 	// the pointer to the field
 	pointer interface{}
 }
@@ -41,6 +150,19 @@ type Option struct {
 // Create a option. Parses the field tags, type and name. Stores a pointer to
 // the field value.
 func NewOption(fieldType reflect.StructField, fieldValue reflect.Value) (*Option, error) {
+	tags, err := ParseTagSet(string(fieldType.Tag))
+
+	if err != nil {
+		err.(*TagSyntaxError).Field = fieldType.Name
+		return nil, err
+	}
+
+	return newOption(fieldType, fieldValue, tags, os.LookupEnv)
+}
+
+// Create a option from the given, already parsed, field tags. Environment
+// variables are looked up using the given function.
+func newOption(fieldType reflect.StructField, fieldValue reflect.Value, tags TagSet, lookupEnv func(string) (string, bool)) (*Option, error) {
 	if !fieldValue.CanAddr() {
 		return nil, errors.New("Cannot address field value: " + fieldType.Name)
 	}
@@ -52,69 +174,203 @@ func NewOption(fieldType reflect.StructField, fieldValue reflect.Value) (*Option
 			"Cannot interface field address: " + fieldType.Name)
 	}
 
-	kind := fieldType.Type.String()
-	pointer := ptrIface.Interface()
-	tags := NewTagSet(string(fieldType.Tag))
-	def := tags["default"]
-	envVar := tags["env"]
+	return defineOption(fieldType.Name, fieldType.Type, ptrIface.Interface(), tags, lookupEnv)
+}
 
-	if envVar != "" {
-		if val, ok := os.LookupEnv(envVar); ok {
-			def = val
-		}
+// Create a option for the field with the given name and type, defined by the
+// given tags and stored at the given pointer. Environment variables are
+// looked up using the given function.
+func defineOption(name string, fieldType reflect.Type, pointer interface{}, tags TagSet, lookupEnv func(string) (string, bool)) (*Option, error) {
+	opt, err := newPrototype(name, fieldType, tags)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if def == "" {
-		// set the default to the current value
-		switch kind {
-		case "bool":
-			var ptr *bool = pointer.(*bool)
-			def = strconv.FormatBool(*ptr)
-		case "float64":
-			var ptr *float64 = pointer.(*float64)
-			def = strconv.FormatFloat(*ptr, 'f', -1, 64)
-		case "int":
-			var ptr *int = pointer.(*int)
-			def = strconv.FormatInt(int64(*ptr), 10)
-		case "int64":
-			var ptr *int64 = pointer.(*int64)
-			def = strconv.FormatInt(*ptr, 10)
-		case "string":
-			var ptr *string = pointer.(*string)
-			def = *ptr
-		case "uint":
-			var ptr *uint = pointer.(*uint)
-			def = strconv.FormatUint(uint64(*ptr), 10)
-		case "uint64":
-			var ptr *uint64 = pointer.(*uint64)
-			def = strconv.FormatUint(*ptr, 10)
+	opt.bind(pointer, lookupEnv)
+	return opt, nil
+}
+
+// Create the prototype of the options for fields with the given name and
+// type, defined by the given tags. Prototypes hold everything which does not
+// depend on the value of the field or the environment, they are bound to a
+// field before use.
+func newPrototype(name string, fieldType reflect.Type, tags TagSet) (*Option, error) {
+	var err error
+	position := 0
+	positional := tags["positional"]
+
+	if positional != "" && positional != "true" && positional != "false" {
+		position, err = strconv.Atoi(positional)
+
+		if err != nil || position < 1 {
+			return nil, errors.New(fmt.Sprintf(
+				"Invalid positional index for field %s: %s",
+				name,
+				positional))
 		}
 	}
 
+	argName := tags["name"]
+
+	if argName == "" {
+		argName = strings.ToUpper(name)
+	}
+
 	opt := Option{
-		Default:     def,
-		Description: tags["description"],
-		Help:        tags["help"],
-		Long:        tags["long"],
-		Name:        fieldType.Name,
-		Short:       tags["short"],
-		Tags:        tags,
-		Type:        kind,
-		pointer:     pointer,
-	}
-
-	if opt.IsPositional() && opt.Type != "[]string" {
-		return nil, errors.New(
-			"Invalid type for positional args: " + opt.Type)
+		Aliases:           splitNames(tags["alias"] + "," + tags["aliases"]),
+		ArgName:           argName,
+		Choices:           splitChoices(tags["choices"]),
+		Counter:           tags["count"] == "true",
+		Default:           tags["default"],
+		Deprecated:        tags["deprecated"],
+		DeprecatedAliases: splitNames(tags["deprecated-aliases"]),
+		Description:       tags["description"],
+		Env:               tags["env"],
+		Help:              tags["help"],
+		Hidden:            tags["hidden"] == "true",
+		Kind:              fieldType.Kind(),
+		Long:              tags["long"],
+		Max:               tags["max"],
+		Min:               tags["min"],
+		Name:              name,
+		Negatable:         tags["negatable"] == "true",
+		Pattern:           tags["pattern"],
+		Position:          position,
+		Required:          tags["required"] == "true",
+		Secret:            tags["secret"] == "true",
+		Short:             tags["short"],
+		Source:            SourceDefault,
+		Tags:              tags,
+		Type:              fieldType.String(),
+	}
+
+	err = opt.check(fieldType)
+
+	if err != nil {
+		return nil, err
 	}
 
 	return &opt, nil
 }
 
-// Adds this option to the flag set, using the defined short/long flags and
-// default value
+// Binds this option to the field the given pointer points to. The default is
+// read from the environment variable of the option if it is set, the current
+// value of the field is the default otherwise.
+func (this *Option) bind(pointer interface{}, lookupEnv func(string) (string, bool)) {
+	if this.Env != "" {
+		if val, ok := lookupEnv(this.Env); ok {
+			this.Default = val
+			this.Source = SourceEnv
+		}
+	}
+
+	if this.Default == "" {
+		// set the default to the current value
+		this.Default = formatDefault(this.Type, pointer)
+	}
+
+	this.pointer = pointer
+}
+
+// Stores the default of this positional option, given by its tags or its
+// environment variable, in its field. Positional args are not added to the
+// flag set, which stores the defaults of the other options.
+func (this *Option) setPositionalDefault() error {
+	if this.Tags["default"] == "" && this.Source != SourceEnv {
+		return nil
+	}
+
+	err := this.store(this.Default)
+
+	if err != nil {
+		return positionalValueError(this, this.Default, err)
+	}
+
+	return nil
+}
+
+// Returns the default of this option given by its tags, or the given initial
+// value of its field if there is none. Unlike the default value, it is not
+// read from the environment variable of the option.
+func (this *Option) readBaseDefault(initial reflect.Value) (reflect.Value, error) {
+	if this.Source != SourceEnv {
+		return this.defaultValue, nil
+	}
+
+	raw := this.Tags["default"]
+
+	if raw == "" {
+		return initial, nil
+	}
+
+	value := reflect.New(initial.Type())
+	err := setValue(value.Interface(), raw)
+
+	if err != nil {
+		return value.Elem(), optionValueError(this, raw, err)
+	}
+
+	return value.Elem(), nil
+}
+
+// Checks the given type of the field of this option is valid for the way it
+// is used
+func (this *Option) check(typ reflect.Type) error {
+	if this.IsPositional() {
+		if !isSupportedType(typ) || (this.Position == 0 && typ.Kind() != reflect.Slice) {
+			return errors.New(
+				"Invalid type for positional args: " + this.Type)
+		}
+
+		variadic := this.Position == 0 ||
+			(typ.Kind() == reflect.Slice && !isTextType(typ))
+
+		if variadic && this.Tags["default"] != "" {
+			return errors.New(fmt.Sprintf(
+				"Invalid positional %s: variadic positional args cannot have a default",
+				this.Name))
+		}
+	}
+
+	if this.Negatable && (this.Type != "bool" || this.Long == "") {
+		return errors.New(fmt.Sprintf(
+			"Invalid negatable option %s: must be a bool with a long name",
+			this.Name))
+	}
+
+	for _, name := range this.DeprecatedAliases {
+		if !containsString(this.Aliases, name) {
+			return errors.New(fmt.Sprintf(
+				"Invalid option %s: deprecated alias %s is not an alias",
+				this.Name,
+				name))
+		}
+	}
+
+	if this.Counter && this.Type != "int" {
+		return errors.New("Invalid type for counter: " + this.Type)
+	}
+
+	return this.checkLimitDefinitions(typ)
+}
+
+// Adds this option to the flag set, using the defined short/long flags, aliases
+// and default value
 func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 	var err error
+	seen := map[string]bool{}
+
+	for _, name := range this.allFlagNames() {
+		if seen[name] || set.Lookup(name) != nil {
+			return errors.New(fmt.Sprintf(
+				"Flag '%s' of option '%s' is already defined.",
+				name,
+				this.Name))
+		}
+
+		seen[name] = true
+	}
 
 	switch this.Type {
 	case "bool":
@@ -128,20 +384,12 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			}
 		}
 
-		if this.Short != "" {
-			set.BoolVar(
-				this.pointer.(*bool),
-				this.Short,
-				def,
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.BoolVar(this.pointer.(*bool), name, def, this.Description)
 		}
 
-		if this.Long != "" {
-			set.BoolVar(
-				this.pointer.(*bool),
-				this.Long,
-				def,
-				this.Description)
+		for _, name := range this.NegatedFlagNames() {
+			set.Var((*negatedValue)(this.pointer.(*bool)), name, this.Description)
 		}
 
 	case "float64":
@@ -155,20 +403,23 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			}
 		}
 
-		if this.Short != "" {
-			set.Float64Var(
-				this.pointer.(*float64),
-				this.Short,
-				def,
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.Float64Var(this.pointer.(*float64), name, def, this.Description)
 		}
 
-		if this.Long != "" {
-			set.Float64Var(
-				this.pointer.(*float64),
-				this.Long,
-				def,
-				this.Description)
+	case "time.Duration":
+		var def time.Duration
+
+		if this.Default != "" {
+			def, err = time.ParseDuration(this.Default)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, name := range this.FlagNames() {
+			set.DurationVar(this.pointer.(*time.Duration), name, def, this.Description)
 		}
 
 	case "int":
@@ -184,20 +435,18 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			def = int(val)
 		}
 
-		if this.Short != "" {
-			set.IntVar(
-				this.pointer.(*int),
-				this.Short,
-				int(def),
-				this.Description)
+		if this.Counter {
+			*this.pointer.(*int) = def
+
+			for _, name := range this.FlagNames() {
+				set.Var((*counterValue)(this.pointer.(*int)), name, this.Description)
+			}
+
+			break
 		}
 
-		if this.Long != "" {
-			set.IntVar(
-				this.pointer.(*int),
-				this.Long,
-				int(def),
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.IntVar(this.pointer.(*int), name, int(def), this.Description)
 		}
 
 	case "int64":
@@ -211,37 +460,13 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			}
 		}
 
-		if this.Short != "" {
-			set.Int64Var(
-				this.pointer.(*int64),
-				this.Short,
-				def,
-				this.Description)
-		}
-
-		if this.Long != "" {
-			set.Int64Var(
-				this.pointer.(*int64),
-				this.Long,
-				def,
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.Int64Var(this.pointer.(*int64), name, def, this.Description)
 		}
 
 	case "string":
-		if this.Short != "" {
-			set.StringVar(
-				this.pointer.(*string),
-				this.Short,
-				this.Default,
-				this.Description)
-		}
-
-		if this.Long != "" {
-			set.StringVar(
-				this.pointer.(*string),
-				this.Long,
-				this.Default,
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.StringVar(this.pointer.(*string), name, this.Default, this.Description)
 		}
 
 	case "uint":
@@ -257,20 +482,8 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			def = uint(val)
 		}
 
-		if this.Short != "" {
-			set.UintVar(
-				this.pointer.(*uint),
-				this.Short,
-				uint(def),
-				this.Description)
-		}
-
-		if this.Long != "" {
-			set.UintVar(
-				this.pointer.(*uint),
-				this.Long,
-				uint(def),
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.UintVar(this.pointer.(*uint), name, uint(def), this.Description)
 		}
 
 	case "uint64":
@@ -284,20 +497,8 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 			}
 		}
 
-		if this.Short != "" {
-			set.Uint64Var(
-				this.pointer.(*uint64),
-				this.Short,
-				def,
-				this.Description)
-		}
-
-		if this.Long != "" {
-			set.Uint64Var(
-				this.pointer.(*uint64),
-				this.Long,
-				def,
-				this.Description)
+		for _, name := range this.FlagNames() {
+			set.Uint64Var(this.pointer.(*uint64), name, def, this.Description)
 		}
 
 	default:
@@ -308,7 +509,146 @@ func (this *Option) AddToFlagSet(set *flag.FlagSet) error {
 	return nil
 }
 
+// Returns the names this option is registered with in a flag set, short name
+// first, followed by the long name and aliases
+func (this *Option) FlagNames() []string {
+	names := []string{}
+
+	if this.Short != "" {
+		names = append(names, this.Short)
+	}
+
+	if this.Long != "" {
+		names = append(names, this.Long)
+	}
+
+	return append(names, this.Aliases...)
+}
+
+// Returns the current value of the option
+func (this *Option) Value() interface{} {
+	if this.pointer == nil {
+		return nil
+	}
+
+	return reflect.ValueOf(this.pointer).Elem().Interface()
+}
+
+// Returns the names of the flags turning this option off, i.e. "no-color".
+// Only negatable options have negated flags.
+func (this *Option) NegatedFlagNames() []string {
+	if !this.Negatable || this.Long == "" {
+		return []string{}
+	}
+
+	return []string{"no-" + this.Long}
+}
+
+// Returns the flag names, negated flag names and secret file flag names of
+// this option
+func (this *Option) allFlagNames() []string {
+	names := append(this.FlagNames(), this.NegatedFlagNames()...)
+	return append(names, this.SecretFileFlagNames()...)
+}
+
+// Returns true if using this option with the given flag name is deprecated,
+// either because the option is or because the name is a deprecated alias
+func (this *Option) IsDeprecatedName(name string) bool {
+	if len(this.DeprecatedAliases) > 0 {
+		return containsString(this.DeprecatedAliases, name)
+	}
+
+	return this.Deprecated != ""
+}
+
 // Returns true if this Option is for storing positional args
 func (this *Option) IsPositional() bool {
-	return this.Tags["positional"] == "true"
+	positional := this.Tags["positional"]
+	return positional != "" && positional != "false"
+}
+
+// Returns true if this Option stores all remaining positional args, either
+// as a slice with a positional index or as the leftover args
+func (this *Option) IsVariadic() bool {
+	if !this.IsPositional() || this.pointer == nil {
+		return false
+	}
+
+	if this.Position == 0 {
+		return true
+	}
+
+	if this.binding != nil {
+		return this.binding.slice()
+	}
+
+	typ := reflect.TypeOf(this.pointer).Elem()
+
+	return typ.Kind() == reflect.Slice &&
+		!reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
+
+// Formats the value the given pointer points to as a default, based on the
+// type of the value. Returns an empty string for types which cannot be
+// formatted.
+func formatDefault(kind string, pointer interface{}) string {
+	switch kind {
+	case "bool":
+		var ptr *bool = pointer.(*bool)
+		return strconv.FormatBool(*ptr)
+	case "float64":
+		var ptr *float64 = pointer.(*float64)
+		return strconv.FormatFloat(*ptr, 'f', -1, 64)
+	case "int":
+		var ptr *int = pointer.(*int)
+		return strconv.FormatInt(int64(*ptr), 10)
+	case "int64":
+		var ptr *int64 = pointer.(*int64)
+		return strconv.FormatInt(*ptr, 10)
+	case "string":
+		var ptr *string = pointer.(*string)
+		return *ptr
+	case "time.Duration":
+		var ptr *time.Duration = pointer.(*time.Duration)
+		return ptr.String()
+	case "uint":
+		var ptr *uint = pointer.(*uint)
+		return strconv.FormatUint(uint64(*ptr), 10)
+	case "uint64":
+		var ptr *uint64 = pointer.(*uint64)
+		return strconv.FormatUint(*ptr, 10)
+	}
+
+	return ""
+}
+
+// Splits a comma separated list of flag names, removing any whitespace and
+// leading dashes
+func splitNames(raw string) []string {
+	names := []string{}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Splits the given comma separated list of choices
+func splitChoices(raw string) []string {
+	choices := []string{}
+
+	for _, choice := range strings.Split(raw, ",") {
+		choice = strings.TrimSpace(choice)
+
+		if choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+
+	return choices
 }
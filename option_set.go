@@ -1,12 +1,19 @@
 package opts
 
 import (
+	"bufio"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type OptionSet struct {
@@ -15,10 +22,80 @@ type OptionSet struct {
 
 	// the flags for this set
 	flags *flag.FlagSet
+
+	// the options in this set, keyed by each of their flag names
+	names map[string]*Option
+
+	// the options in this set, in the order they were defined
+	order []*Option
+
+	// the struct the options were read from, invalid for empty sets
+	data reflect.Value
+
+	// guards the option values while they are written or copied
+	lock sync.RWMutex
+
+	// true if a copy of the struct is kept after every change of the values
+	snapshots bool
+
+	// true if required options are listed as required in the JSON schema
+	schemaRequired bool
+
+	// the copy of the struct made after the last change of the values
+	snapshot atomic.Value
+
+	// the paths of the config files option values are read from
+	configFiles []string
+
+	// returns the args parsed when Parse is given nil
+	args func() []string
+
+	// looks up the value of an environment variable
+	lookupEnv func(name string) (string, bool)
+
+	// the file system config files, arg files and secret files are read from
+	fsys fs.FS
+
+	// the writer help is printed to
+	stdout io.Writer
+
+	// derives the long names of unnamed options, if set
+	naming NamingStrategy
+
+	// true if bool options are negatable by default
+	negatable bool
+
+	// true if args starting with "@" are expanded from arg files
+	argFiles bool
+
+	// true if unknown tag keys should be reported
+	strict bool
+
+	// the tag key all option tags are read from, empty to read the field tags
+	namespace string
+
+	// called when a deprecated option is used, if set
+	onDeprecated func(opt *Option, name string)
+
+	// true if missing required options are prompted for
+	prompting bool
+
+	// the writer prompts are written to
+	prompts io.Writer
+
+	// the reader secrets given as "-" and answers to prompts are read from
+	stdin io.Reader
+
+	// buffers stdin while reading secrets line by line
+	stdinReader *bufio.Reader
+
+	// the writer warnings are written to
+	warnings io.Writer
 }
 
-// Creates a new OptionSet for the given struct
-func NewOptionSet(data interface{}) (*OptionSet, error) {
+// Creates a new OptionSet for the given struct, configured using the given
+// settings
+func NewOptionSet(data interface{}, settings ...Setting) (*OptionSet, error) {
 	dataType := reflect.TypeOf(data)
 
 	if dataType.Kind() != reflect.Ptr {
@@ -28,43 +105,287 @@ func NewOptionSet(data interface{}) (*OptionSet, error) {
 	dataType = dataType.Elem()
 	dataValue := reflect.ValueOf(data).Elem()
 
+	set := NewEmptyOptionSet(dataType.Name(), settings...)
+	set.data = dataValue
+	options, err := set.readStruct(dataType, dataValue)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = set.addOptions(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Validates the given options and adds them to this set, in order
+func (this *OptionSet) addOptions(options []*Option) error {
+	err := validateDefinitions(options)
+
+	if err != nil {
+		return err
+	}
+
+	for _, opt := range options {
+		err = this.register(opt)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	this.publish()
+	return nil
+}
+
+// Creates the options for the fields of the given struct, in declaration
+// order. The fields are read from the cached options of the struct type, only
+// binding the options to the fields of the given value and reading their
+// defaults happens for every set.
+func (this *OptionSet) readStruct(dataType reflect.Type, dataValue reflect.Value) ([]*Option, error) {
+	cached := cachedFields(dataType, this.namespace)
+	options := make([]*Option, 0, len(cached.fields))
+
+	for _, field := range cached.fields {
+		opt, err := field.bind(dataValue, this.lookupEnv)
+
+		if err != nil {
+			return nil, err
+		}
+
+		this.place(opt, field.path, field.group)
+		options = append(options, opt)
+	}
+
+	if cached.err != nil {
+		return nil, cached.err
+	}
+
+	return options, nil
+}
+
+// Moves the given option of a field into the given group, naming it by the
+// path of the field, and applies the settings of this set to it
+func (this *OptionSet) place(opt *Option, path, group string) {
+	opt.Group = group
+	opt.Name = joinPath(path, opt.Name)
+
+	if this.strict {
+		for _, key := range opt.Tags.UnknownKeys() {
+			fmt.Fprintf(this.warnings,
+				"Unknown tag '%s' on field %s\n",
+				key,
+				opt.Name)
+		}
+	}
+
+	this.prepare(opt)
+}
+
+// Creates a new OptionSet without any options, configured using the given
+// settings. Options are added using AddOption or the typed helpers.
+func NewEmptyOptionSet(name string, settings ...Setting) *OptionSet {
 	set := OptionSet{
-		Options: map[string]*Option{},
-		flags:   flag.NewFlagSet(dataType.Name(), flag.ContinueOnError),
+		Options:   map[string]*Option{},
+		flags:     flag.NewFlagSet(name, flag.ContinueOnError),
+		names:     map[string]*Option{},
+		args:      func() []string { return os.Args[1:] },
+		lookupEnv: os.LookupEnv,
+		fsys:      osFS{},
+		prompts:   os.Stderr,
+		stdin:     os.Stdin,
+		stdout:    os.Stdout,
+		warnings:  os.Stderr,
+	}
+
+	for _, setting := range settings {
+		setting(&set)
 	}
 
 	// flags package outputs to os.Stderr in certain cases, stifle this by
 	// setting it to write to /dev/null
 	set.flags.SetOutput(ioutil.Discard)
 
-	for n := 0; n < dataType.NumField(); n++ {
-		fieldType := dataType.Field(n)
+	return &set
+}
 
-		// ignore field without tags
-		if fieldType.Tag == "" {
-			continue
+// Applies the settings of this set which change how the given option is named
+func (this *OptionSet) prepare(opt *Option) {
+	if this.naming != nil && !opt.IsPositional() && len(opt.FlagNames()) == 0 {
+		opt.Long = this.naming(opt.Name)
+	}
+
+	if this.negatable && opt.Type == "bool" && opt.Long != "" &&
+		opt.Tags["negatable"] != "false" && !opt.IsPositional() {
+		opt.Negatable = true
+	}
+}
+
+// Adds the given option to this set and its flags to the flag set
+func (this *OptionSet) register(opt *Option) error {
+	var value, initial reflect.Value
+
+	// options bound without reflection store their defaults in their binding
+	if opt.binding == nil {
+		value = reflect.ValueOf(opt.pointer).Elem()
+		initial = cloneValue(value)
+	}
+
+	// skip adding positional args to FlagSet
+	if opt.IsPositional() {
+		err := opt.setPositionalDefault()
+
+		if err != nil {
+			return err
 		}
+	} else {
+		err := opt.AddToFlagSet(this.flags)
 
-		fieldValue := dataValue.Field(n)
-		opt, err := NewOption(fieldType, fieldValue)
+		if err != nil && opt.Secret {
+			return redactError(err, opt.Default)
+		} else if err != nil {
+			return err
+		}
+
+		if opt.Secret {
+			this.registerSecret(opt)
+		}
+
+		for _, name := range opt.allFlagNames() {
+			this.names[name] = opt
+		}
+	}
+
+	// the defaults are the values after the flags are defined
+	opt.defaultSource = opt.Source
+
+	if opt.binding != nil {
+		opt.binding.save()
+	} else {
+		opt.defaultValue = reflect.New(value.Type()).Elem()
+		opt.defaultValue.Set(value)
+		baseDefault, err := opt.readBaseDefault(initial)
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		// skip adding positional args to FlagSet
-		if !opt.IsPositional() {
-			err = opt.AddToFlagSet(set.flags)
+		opt.baseDefault = baseDefault
+	}
+
+	this.Options[opt.Name] = opt
+	this.order = append(this.order, opt)
+	return nil
+}
+
+// Returns the options of this set in the order they were defined
+func (this *OptionSet) List() []*Option {
+	return append([]*Option{}, this.order...)
+}
+
+// Returns the option with the given field name (i.e. "Verbose") or flag name,
+// with or without dashes (i.e. "-v", "--verbose" or "verbose"). Returns nil if
+// there is no such option.
+func (this *OptionSet) Lookup(name string) *Option {
+	if opt, ok := this.Options[name]; ok {
+		return opt
+	}
+
+	return this.names[strings.TrimLeft(name, "-")]
+}
 
-			if err != nil {
-				return nil, err
+// Checks the given options for conflicting flag names, duplicate positional
+// fields and options that cannot be reached by any flag. Returns a
+// DefinitionError listing all of the problems found.
+func validateDefinitions(options []*Option) error {
+	problems := []string{}
+	owners := map[string]*Option{}
+	positions := map[int]*Option{}
+	var variadic *Option
+
+	for _, opt := range options {
+		if opt.IsPositional() {
+			if opt.IsVariadic() && variadic != nil {
+				problems = append(problems, fmt.Sprintf(
+					"Options '%s' and '%s' both store positional args",
+					variadic.Name,
+					opt.Name))
+			} else if opt.IsVariadic() {
+				variadic = opt
+			}
+
+			if other, ok := positions[opt.Position]; ok && opt.Position > 0 {
+				problems = append(problems, fmt.Sprintf(
+					"Options '%s' and '%s' both use positional index %d",
+					other.Name,
+					opt.Name,
+					opt.Position))
+			} else if opt.Position > 0 {
+				positions[opt.Position] = opt
+			}
+
+			continue
+		}
+
+		names := opt.allFlagNames()
+
+		if len(names) == 0 {
+			problems = append(problems, fmt.Sprintf(
+				"Option '%s' has no short, long or alias name",
+				opt.Name))
+		}
+
+		for _, name := range names {
+			owner, ok := owners[name]
+
+			switch {
+			case !ok:
+				owners[name] = opt
+			case owner == opt:
+				problems = append(problems, fmt.Sprintf(
+					"Flag '%s' is defined more than once by option '%s'",
+					name,
+					opt.Name))
+			default:
+				problems = append(problems, fmt.Sprintf(
+					"Flag '%s' is defined by options '%s' and '%s'",
+					name,
+					owner.Name,
+					opt.Name))
 			}
 		}
+	}
+
+	var optional, previous *Option
+
+	for _, opt := range sortPositionals(options) {
+		if previous != nil && previous.IsVariadic() && !opt.IsVariadic() {
+			problems = append(problems, fmt.Sprintf(
+				"Variadic positional '%s' must be the last positional",
+				previous.Name))
+		}
+
+		if opt.Required && optional != nil {
+			problems = append(problems, fmt.Sprintf(
+				"Required positional '%s' follows optional positional '%s'",
+				opt.Name,
+				optional.Name))
+		} else if !opt.Required && optional == nil {
+			optional = opt
+		}
+
+		previous = opt
+	}
 
-		set.Options[opt.Name] = opt
+	if len(problems) > 0 {
+		return &DefinitionError{Problems: problems}
 	}
 
-	return &set, nil
+	return nil
 }
 
 // Checks if the OptionSet has options
@@ -73,7 +394,7 @@ func (this *OptionSet) HasOptions() bool {
 		return false
 	}
 
-	for _, opt := range this.Options {
+	for _, opt := range this.order {
 		if !opt.IsPositional() {
 			return true
 		}
@@ -88,7 +409,7 @@ func (this *OptionSet) HasPositional() bool {
 		return false
 	}
 
-	for _, opt := range this.Options {
+	for _, opt := range this.order {
 		if opt.IsPositional() {
 			return true
 		}
@@ -97,35 +418,332 @@ func (this *OptionSet) HasPositional() bool {
 	return false
 }
 
-// Parses the given args using this OptionSet
+// Parses the given args using this OptionSet. Holds the write lock of the
+// set while the option values are written. Can be called more than once, each
+// call starts from the default values, like Reset.
 func (this *OptionSet) Parse(args []string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	err := this.parse(args)
+
+	if err != nil {
+		return err
+	}
+
+	this.publish()
+	return nil
+}
+
+// Parses the given args, without locking. The option values set by earlier
+// parses are reset first.
+func (this *OptionSet) parse(args []string) error {
+	if this.flags.Parsed() {
+		this.reset()
+	}
+
 	if args == nil {
-		args = os.Args[1:]
+		args = this.args()
 	}
 
-	err := this.flags.Parse(args)
+	if this.argFiles {
+		expanded, err := this.expandArgFiles(args)
+
+		if err != nil {
+			return err
+		}
+
+		args = expanded
+	}
+
+	err := this.flags.Parse(this.expandShortFlags(args))
+
+	if err != nil {
+		return redactError(err, this.secretArgs(args)...)
+	}
+
+	this.flags.Visit(func(f *flag.Flag) {
+		opt := this.names[f.Name]
+
+		if opt == nil {
+			return
+		}
+
+		opt.Source = SourceFlag
+
+		if opt.IsDeprecatedName(f.Name) {
+			this.warnDeprecated(opt, f.Name)
+		}
+	})
+
+	err = this.applyConfigFiles()
 
 	if err != nil {
 		return err
 	}
 
-	leftovers := this.flags.Args()
+	err = this.parsePositionals(this.flags.Args())
 
-	for _, opt := range this.Options {
-		if opt.IsPositional() {
-			var ptr *[]string = opt.pointer.(*[]string)
-			*ptr = leftovers
+	if err != nil {
+		return err
+	}
+
+	err = this.checkRequired()
+
+	if err != nil {
+		return err
+	}
+
+	return this.checkValues()
+}
+
+// Expands bundled short flags into separate args, i.e. "-vvv" into "-v -v -v".
+// Args are only expanded if every bundled flag is a bool or counter flag.
+func (this *OptionSet) expandShortFlags(args []string) []string {
+	expanded := []string{}
+
+	for n := 0; n < len(args); n++ {
+		arg := args[n]
+
+		// flag parsing stops at the first non-flag arg
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			return append(expanded, args[n:]...)
+		}
+
+		name := strings.TrimLeft(arg, "-")
+
+		if strings.Contains(name, "=") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		if f := this.flags.Lookup(name); f != nil {
+			expanded = append(expanded, arg)
+
+			// keep the value of non-bool flags, even if it looks like a flag
+			if !isBoolFlag(f) && n+1 < len(args) {
+				expanded = append(expanded, args[n+1])
+				n++
+			}
+
+			continue
+		}
+
+		if arg[1] == '-' || !this.isBundle(name) {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		for _, short := range name {
+			expanded = append(expanded, "-"+string(short))
+		}
+	}
+
+	return expanded
+}
+
+// Returns true if every letter of the given name is a bool or counter flag
+func (this *OptionSet) isBundle(name string) bool {
+	for _, short := range name {
+		f := this.flags.Lookup(string(short))
+
+		if f == nil || !isBoolFlag(f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Stores the given leftover args in the positional options, in order of their
+// positional index. Any remaining args are stored in the variadic option.
+func (this *OptionSet) parsePositionals(args []string) error {
+	positionals := sortPositionals(this.order)
+	variadic := false
+
+	for _, opt := range positionals {
+		if opt.IsVariadic() {
+			variadic = true
+
+			if opt.binding != nil {
+				opt.binding.clear(len(args))
+			} else {
+				value := reflect.ValueOf(opt.pointer).Elem()
+				value.Set(reflect.MakeSlice(value.Type(), 0, len(args)))
+			}
+
+			if len(args) == 0 && opt.Required && this.canPrompt() {
+				err := this.prompt(opt)
+
+				if err != nil {
+					return err
+				}
+
+				continue
+			} else if len(args) == 0 && opt.Required {
+				return &MissingPositionalError{Option: opt}
+			}
+
+			for _, arg := range args {
+				err := opt.store(arg)
+
+				if err != nil {
+					return positionalValueError(opt, arg, err)
+				}
+			}
+
+			if len(args) > 0 {
+				opt.Source = SourcePositional
+			}
+
+			args = nil
+			continue
 		}
+
+		if len(args) == 0 {
+			if opt.Required && this.canPrompt() {
+				err := this.prompt(opt)
+
+				if err != nil {
+					return err
+				}
+			} else if opt.Required {
+				return &MissingPositionalError{Option: opt}
+			}
+
+			continue
+		}
+
+		err := opt.store(args[0])
+
+		if err != nil {
+			return positionalValueError(opt, args[0], err)
+		}
+
+		opt.Source = SourcePositional
+
+		args = args[1:]
+	}
+
+	// leftover args are ignored unless ordered positionals are used
+	if !variadic && len(positionals) > 0 && len(args) > 0 {
+		return &ExtraPositionalError{Args: args}
 	}
 
 	return nil
 }
 
-// Writes the default options and descriptions to the given io.Writer
+// Returns a usage line for the options and positional args of this set, i.e.
+// "[options] SRC [DST] [FILES...]"
+func (this *OptionSet) Usage() string {
+	parts := []string{}
+
+	if this.HasOptions() {
+		parts = append(parts, "[options]")
+	}
+
+	for _, opt := range sortPositionals(this.order) {
+		part := opt.ArgName
+
+		if opt.IsVariadic() {
+			part += "..."
+		}
+
+		if !opt.Required {
+			part = "[" + part + "]"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Prints the help of this set to its standard output, see WriteHelp
+func (this *OptionSet) PrintHelp() {
+	this.WriteHelp(this.stdout)
+}
+
+// Writes the default options and descriptions to the given io.Writer, in the
+// order the options were defined. Hidden options are left out. Holds the read
+// lock of the set, as parsing replaces the flags the defaults are read from.
 func (this *OptionSet) WriteHelp(out io.Writer) {
-	this.flags.SetOutput(out)
-	this.flags.PrintDefaults()
-	// flags package outputs to os.Stderr in certain cases, stifle this by
-	// setting it to write to /dev/null
-	this.flags.SetOutput(ioutil.Discard)
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	for _, opt := range this.order {
+		if !opt.IsPositional() && !opt.Hidden {
+			opt.writeHelp(out, this.flags)
+		}
+	}
+}
+
+// Reports the use of the given deprecated option, using the deprecation
+// handler if one is set, or writing a warning otherwise
+func (this *OptionSet) warnDeprecated(opt *Option, name string) {
+	if this.onDeprecated != nil {
+		this.onDeprecated(opt, name)
+		return
+	}
+
+	msg := fmt.Sprintf("Flag '%s' is deprecated", name)
+
+	if opt.Deprecated != "" && opt.Deprecated != "true" {
+		msg += ": " + opt.Deprecated
+	}
+
+	fmt.Fprintln(this.warnings, msg)
+}
+
+// Returns the positional options from the given options, ordered by their
+// positional index. Options storing the leftover args come last.
+func sortPositionals(options []*Option) []*Option {
+	positionals := []*Option{}
+
+	for _, opt := range options {
+		if opt.IsPositional() {
+			positionals = append(positionals, opt)
+		}
+	}
+
+	sort.SliceStable(positionals, func(i, j int) bool {
+		a, b := positionals[i].Position, positionals[j].Position
+		return a != 0 && (b == 0 || a < b)
+	})
+
+	return positionals
+}
+
+// Creates the error returned when the given positional arg cannot be parsed
+func positionalValueError(opt *Option, arg string, err error) error {
+	if opt.Secret {
+		return redactError(errors.New(fmt.Sprintf(
+			"Invalid value for positional arg %s: %s",
+			opt.ArgName,
+			err.Error())), arg)
+	}
+
+	return errors.New(fmt.Sprintf(
+		"Invalid value '%s' for positional arg %s: %s",
+		arg,
+		opt.ArgName,
+		err.Error()))
+}
+
+// Returns true if the given flag does not take a value
+func isBoolFlag(f *flag.Flag) bool {
+	value, ok := f.Value.(interface {
+		IsBoolFlag() bool
+	})
+
+	return ok && value.IsBoolFlag()
+}
+
+// Joins the given path and name with a dot, i.e. "Database.Host"
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
 }
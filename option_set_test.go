@@ -3,7 +3,10 @@ package opts
 import (
 	"bytes"
 	"github.com/stretchr/testify/require"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type TestOptionSetStruct struct {
@@ -46,6 +49,161 @@ type TestInvalidOptionSetStruct struct {
 
 type TestEmptyOptionSetStruct struct{}
 
+type TestAliasesOptionSetStruct struct {
+	DryRun bool `
+        aliases:"--dry,-N"
+        description:"Only pretend."
+        long:"dry-run"
+        short:"d"`
+
+	Quiet bool `description:"Be quiet." short:"q"`
+}
+
+type TestAliasConflictOptionSetStruct struct {
+	DryRun bool `aliases:"-q" long:"dry-run"`
+
+	Quiet bool `short:"q"`
+}
+
+type TestDefinitionErrorsOptionSetStruct struct {
+	Args []string `positional:"true"`
+
+	Rest []string `positional:"true"`
+
+	Verbose bool `long:"verbose" short:"v"`
+
+	Version bool `long:"version" short:"v"`
+
+	Loud bool `aliases:"--loud" long:"loud"`
+
+	Unreachable string `env:"UNREACHABLE"`
+}
+
+type TestOtherTagsOptionSetStruct struct {
+	Name string `json:"name" long:"name"`
+
+	ID string `db:"id" json:"id"`
+}
+
+type TestPositionalOptionSetStruct struct {
+	Source string `name:"SRC" positional:"1" required:"true"`
+
+	Count int `name:"COUNT" positional:"2"`
+
+	Timeout time.Duration `name:"TIMEOUT" positional:"3"`
+
+	Verbose bool `short:"v"`
+}
+
+type TestVariadicOptionSetStruct struct {
+	Source string `name:"SRC" positional:"1" required:"true"`
+
+	Sizes []uint `name:"SIZE" positional:"2" required:"true"`
+}
+
+type TestPositionalDefaultOptionSetStruct struct {
+	Source string `name:"SRC" positional:"1" required:"true"`
+
+	Port int `default:"80" name:"PORT" positional:"2"`
+
+	Host string `env:"TEST_POSITIONAL_HOST" name:"HOST" positional:"3"`
+}
+
+type TestVariadicDefaultOptionSetStruct struct {
+	Files []string `default:"a" positional:"1"`
+}
+
+type TestInvalidPositionalDefaultOptionSetStruct struct {
+	Port int `default:"http" positional:"1"`
+}
+
+type TestInvalidPositionalOptionSetStruct struct {
+	Files []string `positional:"1"`
+
+	Source string `positional:"2" required:"true"`
+
+	Dest string `positional:"2"`
+}
+
+type TestCounterOptionSetStruct struct {
+	Args []string `positional:"true"`
+
+	Name string `long:"name" short:"n"`
+
+	Quiet bool `short:"q"`
+
+	Verbose int `
+        count:"true"
+        description:"The verbosity level."
+        long:"verbose"
+        short:"v"`
+}
+
+type TestNegatableOptionSetStruct struct {
+	Color bool `
+        default:"true"
+        description:"Colorize the output."
+        long:"color"
+        negatable:"true"`
+
+	Pager bool `default:"true" long:"pager"`
+
+	Quiet bool `long:"quiet" negatable:"false"`
+}
+
+type TestNegatableConflictOptionSetStruct struct {
+	Color bool `long:"color" negatable:"true"`
+
+	NoColor bool `long:"no-color"`
+}
+
+type TestInvalidTagOptionSetStruct struct {
+	Name string `long:"name`
+}
+
+type TestGroupOptionSetStruct struct {
+	Name string `long:"name" short:"n"`
+
+	Database TestGroupDatabaseStruct `group:"database"`
+
+	Files []string `positional:"true"`
+
+	Verbose bool `env:"TEST_GROUP_VERBOSE" long:"verbose" short:"v"`
+}
+
+type TestGroupDatabaseStruct struct {
+	Host string `default:"localhost" long:"db-host"`
+
+	Port int `default:"5432" long:"db-port"`
+}
+
+type TestDeprecatedOptionStruct struct {
+	Old bool `alias:"legacy" deprecated:"true" long:"old"`
+}
+
+type TestDeprecatedAliasInvalidStruct struct {
+	Simulate bool `alias:"dry-run" deprecated-aliases:"dry" long:"simulate"`
+}
+
+type TestDeprecatedOptionSetStruct struct {
+	Name string `
+        alias:"nom"
+        description:"The name to use"
+        long:"name"`
+
+	Simulate bool `
+        alias:"dry-run"
+        deprecated:"use --simulate"
+        deprecated-aliases:"dry-run"
+        description:"Only pretend."
+        long:"simulate"`
+
+	Secret string `
+        description:"Not for you."
+        hidden:"true"
+        long:"secret"`
+}
+
 type TestParseDefaultArgsStruct struct {
 	CoverageOut     string `long:"test.outputdir"`
 	CoverageProfile string `long:"test.coverprofile"`
@@ -172,10 +330,420 @@ func TestOptionSetWriteHelp(t *testing.T) {
 	buf := bytes.Buffer{}
 	set.WriteHelp(&buf)
 
-	expected := "  -n string\n    \tThe name to use (default \"foo\")\n  " +
-		"-name string\n    \tThe name to use (default \"foo\")\n  " +
-		"-v\tUse verbose logging.\n  -verbose\n    \tUse verbose " +
-		"logging.\n"
+	expected := "  -n, --name string\n    \tThe name to use (default " +
+		"\"foo\")\n  -v, --verbose\n    \tUse verbose logging.\n"
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetParse_Alias(t *testing.T) {
+	opts := TestDeprecatedOptionSetStruct{}
+	set, err := NewOptionSet(&opts, WithWarningOutput(&bytes.Buffer{}))
+	require.Nil(t, err)
+	err = set.Parse([]string{"--nom", "bar"})
+	require.Nil(t, err)
+	require.Equal(t, "bar", opts.Name)
+}
+
+func TestOptionSetParse_Deprecated(t *testing.T) {
+	opts := TestDeprecatedOptionSetStruct{}
+	buf := bytes.Buffer{}
+	set, err := NewOptionSet(&opts, WithWarningOutput(&buf))
+	require.Nil(t, err)
+	err = set.Parse([]string{"--dry-run", "--name", "bar"})
+	require.Nil(t, err)
+	require.True(t, opts.Simulate)
+	require.Equal(t,
+		"Flag 'dry-run' is deprecated: use --simulate\n",
+		buf.String())
+}
+
+func TestOptionSetParse_Deprecated_Handler(t *testing.T) {
+	opts := TestDeprecatedOptionSetStruct{}
+	used := []string{}
+	set, err := NewOptionSet(&opts,
+		WithDeprecationHandler(func(opt *Option, name string) {
+			used = append(used, opt.Name+":"+name)
+		}))
+	require.Nil(t, err)
+	err = set.Parse([]string{"--simulate"})
+	require.Nil(t, err)
+	require.True(t, opts.Simulate)
+	require.Equal(t, []string{}, used)
+
+	err = set.Parse([]string{"--dry-run"})
+	require.Nil(t, err)
+	require.True(t, opts.Simulate)
+	require.Equal(t, []string{"Simulate:dry-run"}, used)
+}
+
+func TestOptionSetParse_Deprecated_Option(t *testing.T) {
+	opts := TestDeprecatedOptionStruct{}
+	buf := bytes.Buffer{}
+	set, err := NewOptionSet(&opts, WithWarningOutput(&buf))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--old", "--legacy"}))
+	require.Equal(t,
+		"Flag 'legacy' is deprecated\nFlag 'old' is deprecated\n",
+		buf.String())
+}
+
+func TestNewOptionSet_DeprecatedAliasInvalid(t *testing.T) {
+	_, err := NewOptionSet(&TestDeprecatedAliasInvalidStruct{})
+	require.NotNil(t, err)
+	require.Equal(t,
+		"Invalid option Simulate: deprecated alias dry is not an alias",
+		err.Error())
+}
+
+func TestOptionSetWriteHelp_Hidden(t *testing.T) {
+	set, err := NewOptionSet(&TestDeprecatedOptionSetStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	require.NotContains(t, buf.String(), "secret")
+	require.Contains(t, buf.String(), "-simulate")
+}
+
+func TestOptionSetWriteHelp_Aliases(t *testing.T) {
+	set, err := NewOptionSet(&TestAliasesOptionSetStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+
+	expected := "  -d, --dry-run, --dry, -N\n    \tOnly pretend.\n  " +
+		"-q\tBe quiet.\n"
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetParse_Aliases(t *testing.T) {
+	opts := TestAliasesOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"--dry"})
+	require.Nil(t, err)
+	require.True(t, opts.DryRun)
+
+	opts = TestAliasesOptionSetStruct{}
+	set, err = NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"-N"})
+	require.Nil(t, err)
+	require.True(t, opts.DryRun)
+}
+
+func TestNewOptionSet_AliasConflict(t *testing.T) {
+	_, err := NewOptionSet(&TestAliasConflictOptionSetStruct{})
+	require.NotNil(t, err)
+	require.IsType(t, &DefinitionError{}, err)
+	require.Equal(t,
+		[]string{"Flag 'q' is defined by options 'DryRun' and 'Quiet'"},
+		err.(*DefinitionError).Problems)
+}
+
+func TestNewOptionSet_DefinitionErrors(t *testing.T) {
+	_, err := NewOptionSet(&TestDefinitionErrorsOptionSetStruct{})
+	require.NotNil(t, err)
+	require.IsType(t, &DefinitionError{}, err)
+	require.Equal(t, []string{
+		"Options 'Args' and 'Rest' both store positional args",
+		"Flag 'v' is defined by options 'Verbose' and 'Version'",
+		"Flag 'loud' is defined more than once by option 'Loud'",
+		"Option 'Unreachable' has no short, long or alias name",
+	}, err.(*DefinitionError).Problems)
+}
+
+func TestNewOptionSet_OtherTags(t *testing.T) {
+	opts := TestOtherTagsOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(set.Options))
+	require.Nil(t, set.Parse([]string{"--name", "foo"}))
+	require.Equal(t, "foo", opts.Name)
+}
+
+func TestOptionSetParse_Positionals(t *testing.T) {
+	opts := TestPositionalOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"-v", "src", "3", "1m"})
+	require.Nil(t, err)
+	require.Equal(t, "src", opts.Source)
+	require.Equal(t, 3, opts.Count)
+	require.Equal(t, time.Minute, opts.Timeout)
+	require.True(t, opts.Verbose)
+}
+
+func TestOptionSetParse_Positionals_Optional(t *testing.T) {
+	opts := TestPositionalOptionSetStruct{Count: 7}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"src"})
+	require.Nil(t, err)
+	require.Equal(t, "src", opts.Source)
+	require.Equal(t, 7, opts.Count)
+}
+
+func TestOptionSetParse_Positionals_Missing(t *testing.T) {
+	set, err := NewOptionSet(&TestPositionalOptionSetStruct{})
+	require.Nil(t, err)
+	err = set.Parse([]string{"-v"})
+	require.IsType(t, &MissingPositionalError{}, err)
+	require.Equal(t, "Source", err.(*MissingPositionalError).Option.Name)
+	require.Equal(t, "Missing positional arg: SRC", err.Error())
+}
+
+func TestOptionSetParse_Positionals_Extra(t *testing.T) {
+	set, err := NewOptionSet(&TestPositionalOptionSetStruct{})
+	require.Nil(t, err)
+	err = set.Parse([]string{"src", "3", "1m", "foo", "bar"})
+	require.IsType(t, &ExtraPositionalError{}, err)
+	require.Equal(t, []string{"foo", "bar"}, err.(*ExtraPositionalError).Args)
+}
+
+func TestOptionSetParse_Positionals_InvalidValue(t *testing.T) {
+	set, err := NewOptionSet(&TestPositionalOptionSetStruct{})
+	require.Nil(t, err)
+	err = set.Parse([]string{"src", "three"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Invalid value 'three' for positional arg COUNT")
+}
+
+func TestOptionSetParse_Positionals_Variadic(t *testing.T) {
+	opts := TestVariadicOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"src", "1", "2", "3"})
+	require.Nil(t, err)
+	require.Equal(t, "src", opts.Source)
+	require.Equal(t, []uint{1, 2, 3}, opts.Sizes)
+
+	err = set.Parse([]string{"src"})
+	require.IsType(t, &MissingPositionalError{}, err)
+}
+
+func TestOptionSetParse_Positionals_Default(t *testing.T) {
+	opts := TestPositionalDefaultOptionSetStruct{}
+	env := func(name string) (string, bool) { return "example.com", name == "TEST_POSITIONAL_HOST" }
+	set, err := NewOptionSet(&opts, WithEnv(env))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"src"}))
+	require.Equal(t, 80, opts.Port)
+	require.Equal(t, "example.com", opts.Host)
+	require.Equal(t, SourceDefault, set.Lookup("Port").Source)
+	require.Equal(t, SourceEnv, set.Lookup("Host").Source)
+
+	require.Nil(t, set.Parse([]string{"src", "8080", "localhost"}))
+	require.Equal(t, 8080, opts.Port)
+	require.Equal(t, "localhost", opts.Host)
+
+	require.Nil(t, set.Parse([]string{"src"}))
+	require.Equal(t, 80, opts.Port)
+	require.Equal(t, "example.com", opts.Host)
+}
+
+func TestNewOptionSet_PositionalDefaultInvalid(t *testing.T) {
+	_, err := NewOptionSet(&TestVariadicDefaultOptionSetStruct{})
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid positional Files: variadic positional args cannot have a default", err.Error())
+
+	_, err = NewOptionSet(&TestInvalidPositionalDefaultOptionSetStruct{})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Invalid value 'http' for positional arg PORT")
+}
+
+func TestNewOptionSet_InvalidPositionals(t *testing.T) {
+	_, err := NewOptionSet(&TestInvalidPositionalOptionSetStruct{})
+	require.IsType(t, &DefinitionError{}, err)
+	require.Equal(t, []string{
+		"Options 'Source' and 'Dest' both use positional index 2",
+		"Variadic positional 'Files' must be the last positional",
+		"Required positional 'Source' follows optional positional 'Files'",
+	}, err.(*DefinitionError).Problems)
+}
+
+func TestOptionSetUsage(t *testing.T) {
+	set, err := NewOptionSet(&TestPositionalOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "[options] SRC [COUNT] [TIMEOUT]", set.Usage())
+
+	set, err = NewOptionSet(&TestVariadicOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "SRC SIZE...", set.Usage())
+
+	set, err = NewOptionSet(&TestOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "[options] [ARGS...]", set.Usage())
+}
+
+func TestOptionSetParse_Counter(t *testing.T) {
+	opts := TestCounterOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"-v", "--verbose", "-v"})
+	require.Nil(t, err)
+	require.Equal(t, 3, opts.Verbose)
+}
+
+func TestOptionSetParse_Counter_Bundled(t *testing.T) {
+	opts := TestCounterOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"-vvqv", "-n", "-vv", "-vv", "foo", "-vv"})
+	require.Nil(t, err)
+	require.Equal(t, 5, opts.Verbose)
+	require.True(t, opts.Quiet)
+	require.Equal(t, "-vv", opts.Name)
+	require.Equal(t, []string{"foo", "-vv"}, opts.Args)
+}
+
+func TestOptionSetParse_Counter_Value(t *testing.T) {
+	opts := TestCounterOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	err = set.Parse([]string{"--verbose=4", "-v"})
+	require.Nil(t, err)
+	require.Equal(t, 5, opts.Verbose)
+}
+
+func TestOptionSetParse_Counter_InvalidBundle(t *testing.T) {
+	set, err := NewOptionSet(&TestCounterOptionSetStruct{})
+	require.Nil(t, err)
+	err = set.Parse([]string{"-vnv"})
+	require.NotNil(t, err)
+}
+
+func TestOptionSetWriteHelp_Counter(t *testing.T) {
+	set, err := NewOptionSet(&TestCounterOptionSetStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	require.Contains(t, buf.String(),
+		"  -v, --verbose\n    \tThe verbosity level. (can be repeated)\n")
+}
+
+func TestOptionSetParse_Negatable(t *testing.T) {
+	opts := TestNegatableOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.flags.Lookup("no-pager"))
+	err = set.Parse([]string{"--no-color"})
+	require.Nil(t, err)
+	require.False(t, opts.Color)
+	require.True(t, opts.Pager)
+}
+
+func TestOptionSetParse_Negatable_Setting(t *testing.T) {
+	opts := TestNegatableOptionSetStruct{}
+	set, err := NewOptionSet(&opts, WithNegatableBools())
+	require.Nil(t, err)
+	require.Nil(t, set.flags.Lookup("no-quiet"))
+	err = set.Parse([]string{"--no-pager", "--no-color=false"})
+	require.Nil(t, err)
+	require.True(t, opts.Color)
+	require.False(t, opts.Pager)
+}
+
+func TestOptionSetWriteHelp_Negatable(t *testing.T) {
+	set, err := NewOptionSet(&TestNegatableOptionSetStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	require.Contains(t, buf.String(),
+		"  --[no-]color\n    \tColorize the output. (default true)\n")
+}
+
+func TestNewOptionSet_NegatableConflict(t *testing.T) {
+	_, err := NewOptionSet(&TestNegatableConflictOptionSetStruct{})
+	require.IsType(t, &DefinitionError{}, err)
+	require.Equal(t,
+		[]string{"Flag 'no-color' is defined by options 'Color' and 'NoColor'"},
+		err.(*DefinitionError).Problems)
+}
+
+func TestNewOptionSet_InvalidTag(t *testing.T) {
+	_, err := NewOptionSet(&TestInvalidTagOptionSetStruct{})
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, "Name", err.(*TagSyntaxError).Field)
+}
+
+func TestOptionSetList(t *testing.T) {
+	set, err := NewOptionSet(&TestGroupOptionSetStruct{})
+	require.Nil(t, err)
+	names := []string{}
+
+	for _, opt := range set.List() {
+		names = append(names, opt.Name)
+	}
+
+	require.Equal(t, []string{
+		"Name", "Database.Host", "Database.Port", "Files", "Verbose",
+	}, names)
+}
+
+func TestOptionSetList_Groups(t *testing.T) {
+	opts := TestGroupOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	opt := set.Lookup("Database.Port")
+	require.NotNil(t, opt)
+	require.Equal(t, "database", opt.Group)
+	require.Equal(t, reflect.Int, opt.Kind)
+	require.Equal(t, "", set.Lookup("Name").Group)
+
+	err = set.Parse([]string{"--db-port", "6543"})
+	require.Nil(t, err)
+	require.Equal(t, 6543, opts.Database.Port)
+	require.Equal(t, "localhost", opts.Database.Host)
+}
+
+func TestOptionSetLookup(t *testing.T) {
+	set, err := NewOptionSet(&TestOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "Verbose", set.Lookup("Verbose").Name)
+	require.Equal(t, "Verbose", set.Lookup("v").Name)
+	require.Equal(t, "Verbose", set.Lookup("-v").Name)
+	require.Equal(t, "Verbose", set.Lookup("--verbose").Name)
+	require.Equal(t, "Args", set.Lookup("Args").Name)
+	require.Nil(t, set.Lookup("--ducks"))
+}
+
+func TestOptionSetLookup_Negated(t *testing.T) {
+	set, err := NewOptionSet(&TestNegatableOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, "Color", set.Lookup("--no-color").Name)
+}
+
+func TestOptionSetParse_Source(t *testing.T) {
+	os.Setenv("TEST_GROUP_VERBOSE", "true")
+	defer os.Unsetenv("TEST_GROUP_VERBOSE")
+	opts := TestGroupOptionSetStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Equal(t, SourceEnv, set.Lookup("Verbose").Source)
+	require.Equal(t, "TEST_GROUP_VERBOSE", set.Lookup("Verbose").Env)
+	require.Equal(t, true, set.Lookup("Verbose").Value())
+
+	err = set.Parse([]string{"-n", "foo", "bar"})
+	require.Nil(t, err)
+	require.Equal(t, SourceFlag, set.Lookup("Name").Source)
+	require.Equal(t, "foo", set.Lookup("Name").Value())
+	require.Equal(t, SourceDefault, set.Lookup("Database.Host").Source)
+	require.Equal(t, SourcePositional, set.Lookup("Files").Source)
+	require.Equal(t, []string{"bar"}, set.Lookup("Files").Value())
+}
+
+func TestOptionSetWriteHelp_Order(t *testing.T) {
+	set, err := NewOptionSet(&TestGroupOptionSetStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+
+	expected := "  -n, --name string\n    \t\n" +
+		"  --db-host string\n    \t (default \"localhost\")\n" +
+		"  --db-port int\n    \t (default 5432)\n" +
+		"  -v, --verbose\n    \t\n"
 
 	require.Equal(t, expected, buf.String())
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type TestNewOptionStruct struct {
@@ -44,6 +45,32 @@ type TestNewOptionStruct struct {
         long:"db"
         default:"mongodb://localhost:27017/db"
         description:"The db resource to connect to."`
+
+	DryRun bool `
+        alias:"--dry, -N"
+        deprecated:"use --simulate"
+        hidden:"true"
+        long:"dry-run"`
+
+	Source string `name:"SRC" positional:"1" required:"true"`
+
+	Timeout time.Duration `positional:"2"`
+
+	BadIndex string `positional:"first"`
+
+	Files []int `positional:"3"`
+
+	Verbosity int `count:"true" short:"V"`
+
+	BadCounter string `count:"true" short:"C"`
+
+	Color bool `long:"color" negatable:"true"`
+
+	BadNegatable bool `negatable:"true" short:"B"`
+
+	BadTag string `long:"bad" short:b`
+
+	Level string `long:"level" choices:"debug, info,,warn"`
 }
 
 func optionTestGetFieldType(num int) reflect.StructField {
@@ -651,3 +678,311 @@ func TestIsPositional_IsNot(t *testing.T) {
 	require.Nil(t, err)
 	require.False(t, opt.IsPositional())
 }
+
+func TestNewOption_Aliases(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(14),
+		optionTestGetFieldValue(14))
+	require.Nil(t, err)
+	require.Equal(t, []string{"dry", "N"}, opt.Aliases)
+}
+
+func TestNewOption_Deprecated(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(14),
+		optionTestGetFieldValue(14))
+	require.Nil(t, err)
+	require.Equal(t, "use --simulate", opt.Deprecated)
+}
+
+func TestNewOption_Hidden(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(14),
+		optionTestGetFieldValue(14))
+	require.Nil(t, err)
+	require.True(t, opt.Hidden)
+
+	opt, err = NewOption(optionTestGetFieldType(0), optionTestGetFieldValue(0))
+	require.Nil(t, err)
+	require.False(t, opt.Hidden)
+}
+
+func TestAddToFlagSet_Aliases(t *testing.T) {
+	var value bool
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Aliases: []string{"dry", "N"},
+		Long:    "dry-run",
+		Type:    "bool",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.Nil(t, err)
+	require.NotNil(t, set.Lookup("dry-run"))
+	require.NotNil(t, set.Lookup("dry"))
+	require.NotNil(t, set.Lookup("N"))
+}
+
+func TestFlagNames(t *testing.T) {
+	opt := Option{
+		Aliases: []string{"dry", "N"},
+		Long:    "dry-run",
+		Short:   "d",
+	}
+
+	require.Equal(t, []string{"d", "dry-run", "dry", "N"}, opt.FlagNames())
+	require.Equal(t, []string{}, (&Option{}).FlagNames())
+}
+
+func TestAddToFlagSet_AlreadyDefined(t *testing.T) {
+	var value bool
+	set := optionTestNewFlagSet()
+	set.Bool("v", false, "")
+	opt := Option{
+		Long:    "verbose",
+		Name:    "Verbose",
+		Short:   "v",
+		Type:    "bool",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.NotNil(t, err)
+	require.Equal(t,
+		"Flag 'v' of option 'Verbose' is already defined.",
+		err.Error())
+	require.Nil(t, set.Lookup("verbose"))
+}
+
+func TestAddToFlagSet_DuplicateAlias(t *testing.T) {
+	var value bool
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Aliases: []string{"verbose"},
+		Long:    "verbose",
+		Name:    "Verbose",
+		Type:    "bool",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.NotNil(t, err)
+	require.Nil(t, set.Lookup("verbose"))
+}
+
+func TestNewOption_Positional_Ordered(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(15),
+		optionTestGetFieldValue(15))
+	require.Nil(t, err)
+	require.True(t, opt.IsPositional())
+	require.False(t, opt.IsVariadic())
+	require.Equal(t, 1, opt.Position)
+	require.Equal(t, "SRC", opt.ArgName)
+	require.True(t, opt.Required)
+}
+
+func TestNewOption_Positional_ArgName_Default(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(16),
+		optionTestGetFieldValue(16))
+	require.Nil(t, err)
+	require.Equal(t, 2, opt.Position)
+	require.Equal(t, "TIMEOUT", opt.ArgName)
+	require.False(t, opt.Required)
+}
+
+func TestNewOption_Positional_InvalidIndex(t *testing.T) {
+	_, err := NewOption(
+		optionTestGetFieldType(17),
+		optionTestGetFieldValue(17))
+	require.NotNil(t, err)
+	require.Equal(t,
+		"Invalid positional index for field BadIndex: first",
+		err.Error())
+}
+
+func TestIsVariadic(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(18),
+		optionTestGetFieldValue(18))
+	require.Nil(t, err)
+	require.True(t, opt.IsVariadic())
+
+	opt, err = NewOption(optionTestGetFieldType(1), optionTestGetFieldValue(1))
+	require.Nil(t, err)
+	require.True(t, opt.IsVariadic())
+
+	opt, err = NewOption(optionTestGetFieldType(0), optionTestGetFieldValue(0))
+	require.Nil(t, err)
+	require.False(t, opt.IsVariadic())
+}
+
+func TestNewOption_Counter(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(19),
+		optionTestGetFieldValue(19))
+	require.Nil(t, err)
+	require.True(t, opt.Counter)
+}
+
+func TestNewOption_Counter_InvalidType(t *testing.T) {
+	_, err := NewOption(
+		optionTestGetFieldType(20),
+		optionTestGetFieldValue(20))
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid type for counter: string", err.Error())
+}
+
+func TestAddToFlagSet_Counter(t *testing.T) {
+	var value int
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Counter: true,
+		Default: "2",
+		Long:    "verbose",
+		Short:   "v",
+		Type:    "int",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.Nil(t, err)
+	require.Equal(t, 2, value)
+	require.Nil(t, set.Parse([]string{"-v", "--verbose"}))
+	require.Equal(t, 4, value)
+}
+
+func TestNewOption_Negatable(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(21),
+		optionTestGetFieldValue(21))
+	require.Nil(t, err)
+	require.True(t, opt.Negatable)
+	require.Equal(t, []string{"no-color"}, opt.NegatedFlagNames())
+}
+
+func TestNewOption_Negatable_Invalid(t *testing.T) {
+	_, err := NewOption(
+		optionTestGetFieldType(22),
+		optionTestGetFieldValue(22))
+	require.NotNil(t, err)
+	require.Equal(t,
+		"Invalid negatable option BadNegatable: must be a bool with a long name",
+		err.Error())
+}
+
+func TestNegatedFlagNames_NotNegatable(t *testing.T) {
+	opt := Option{Long: "color"}
+	require.Equal(t, []string{}, opt.NegatedFlagNames())
+}
+
+func TestAddToFlagSet_Negatable(t *testing.T) {
+	var value bool
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Default:   "true",
+		Long:      "color",
+		Negatable: true,
+		Type:      "bool",
+		pointer:   &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.Nil(t, err)
+	require.NotNil(t, set.Lookup("no-color"))
+	require.Nil(t, set.Parse([]string{"--no-color"}))
+	require.False(t, value)
+}
+
+func TestNewOption_InvalidTag(t *testing.T) {
+	_, err := NewOption(
+		optionTestGetFieldType(23),
+		optionTestGetFieldValue(23))
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t,
+		"Invalid tag for field BadTag at position 17: expected '\"' to "+
+			"start the value of 'short'",
+		err.Error())
+}
+
+func TestAddToFlagSet_Duration(t *testing.T) {
+	var value time.Duration
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Default: "1m",
+		Long:    "timeout",
+		Short:   "t",
+		Type:    "time.Duration",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.Nil(t, err)
+	require.NotNil(t, set.Lookup("t"))
+	require.NotNil(t, set.Lookup("timeout"))
+	require.Equal(t, time.Minute, value)
+}
+
+func TestAddToFlagSet_Duration_Invalid(t *testing.T) {
+	var value time.Duration
+	set := optionTestNewFlagSet()
+	opt := Option{
+		Default: "soon",
+		Long:    "timeout",
+		Type:    "time.Duration",
+		pointer: &value,
+	}
+
+	err := opt.AddToFlagSet(set)
+	require.NotNil(t, err)
+	require.Nil(t, set.Lookup("timeout"))
+}
+
+func TestFormatDefault(t *testing.T) {
+	duration := time.Minute
+	require.Equal(t, "1m0s", formatDefault("time.Duration", &duration))
+	values := []string{}
+	require.Equal(t, "", formatDefault("[]string", &values))
+}
+
+func TestNewOption_Kind(t *testing.T) {
+	opt, err := NewOption(optionTestGetFieldType(0), optionTestGetFieldValue(0))
+	require.Nil(t, err)
+	require.Equal(t, reflect.Bool, opt.Kind)
+}
+
+func TestNewOption_Env(t *testing.T) {
+	opt, err := NewOption(optionTestGetFieldType(4), optionTestGetFieldValue(4))
+	require.Nil(t, err)
+	require.Equal(t, "FLABBERGASTED", opt.Env)
+	require.Equal(t, SourceDefault, opt.Source)
+}
+
+func TestNewOption_Source_Env(t *testing.T) {
+	os.Setenv("FLABBERGASTED", "happy happy joy joy")
+	defer os.Unsetenv("FLABBERGASTED")
+	opt, err := NewOption(optionTestGetFieldType(4), optionTestGetFieldValue(4))
+	require.Nil(t, err)
+	require.Equal(t, SourceEnv, opt.Source)
+}
+
+func TestOptionValue(t *testing.T) {
+	value := "foo"
+	opt := Option{pointer: &value}
+	require.Equal(t, "foo", opt.Value())
+	require.Nil(t, (&Option{}).Value())
+}
+
+func TestNewOption_Choices(t *testing.T) {
+	opt, err := NewOption(
+		optionTestGetFieldType(24),
+		optionTestGetFieldValue(24))
+	require.Nil(t, err)
+	require.Equal(t, []string{"debug", "info", "warn"}, opt.Choices)
+
+	opt, err = NewOption(optionTestGetFieldType(0), optionTestGetFieldValue(0))
+	require.Nil(t, err)
+	require.Equal(t, []string{}, opt.Choices)
+}
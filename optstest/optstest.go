@@ -0,0 +1,201 @@
+// Package optstest provides helpers for testing programs using opts: parsing
+// with a fake environment, capturing help output, asserting error types,
+// golden files and table driven argv-to-struct tests.
+package optstest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"github.com/ronelliott/go-opts"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Set to update golden files. Namespaced, so test packages importing optstest
+// can define their own -update flag.
+var update = flag.Bool("optstest.update", false, "Update the golden files of optstest.Golden")
+
+// The environment variables of a parse, keyed by name
+type Env map[string]string
+
+// Looks up the environment variable with the given name, for use with
+// opts.WithEnv
+func (this Env) Lookup(name string) (string, bool) {
+	value, ok := this[name]
+	return value, ok
+}
+
+// Parses the given args into the given struct, with the given environment
+// variables instead of the ones of the process. The process environment is
+// not changed, so tests using this can run in parallel.
+func Parse(t testing.TB, data interface{}, args []string, env Env, settings ...opts.Setting) (*opts.OptionSet, error) {
+	t.Helper()
+	settings = append([]opts.Setting{opts.WithEnv(env.Lookup)}, settings...)
+	set, err := opts.NewOptionSet(data, settings...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if args == nil {
+		args = []string{}
+	}
+
+	return set, set.Parse(args)
+}
+
+// Parses like Parse, failing the test if parsing fails
+func MustParse(t testing.TB, data interface{}, args []string, env Env, settings ...opts.Setting) *opts.OptionSet {
+	t.Helper()
+	set, err := Parse(t, data, args, env, settings...)
+
+	if err != nil {
+		t.Fatalf("Cannot parse %q: %s", args, err)
+	}
+
+	return set
+}
+
+// Returns the help output of the options of the given struct, with an empty
+// environment. Fails the test if the options are invalid.
+func Help(t testing.TB, data interface{}, settings ...opts.Setting) string {
+	t.Helper()
+	settings = append([]opts.Setting{opts.WithEnv(Env{}.Lookup)}, settings...)
+	set, err := opts.NewOptionSet(data, settings...)
+
+	if err != nil {
+		t.Fatalf("Cannot create options: %s", err)
+		return ""
+	}
+
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	return buf.String()
+}
+
+// Fails the test unless the given error, or an error it wraps, can be
+// assigned to the given target, which is a pointer to an error variable (i.e.
+// a **opts.MissingOptionError). Sets the target to the error.
+func ErrorAs(t testing.TB, err error, target interface{}) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("Expected an error of type %s, got nil", reflect.TypeOf(target).Elem())
+		return
+	}
+
+	if !errors.As(err, target) {
+		t.Fatalf("Expected an error of type %s, got %T: %s", reflect.TypeOf(target).Elem(), err, err)
+	}
+}
+
+// Compares the given output, like help output, to the contents of the golden
+// file "testdata/<name>.golden". Run the tests with -optstest.update to write
+// the output to the golden file instead.
+func Golden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(got), 0644)
+		}
+
+		if err != nil {
+			t.Fatalf("Cannot update golden file %s: %s", path, err)
+		}
+
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Cannot read golden file %s: %s", path, err)
+		return
+	}
+
+	if string(want) != got {
+		t.Errorf("Output differs from golden file %s\nwant:\n%s\ngot:\n%s", path, want, got)
+	}
+}
+
+// Compares the help output of the options of the given struct to the golden
+// file with the given name, see Golden
+func GoldenHelp(t testing.TB, name string, data interface{}, settings ...opts.Setting) {
+	t.Helper()
+	Golden(t, name, Help(t, data, settings...))
+}
+
+// A case of a table driven parse test
+type Case struct {
+	// the name of the case, used as the name of the subtest
+	Name string
+
+	// the args to parse
+	Args []string
+
+	// the environment variables of the options
+	Env Env
+
+	// the struct expected after parsing, a pointer or value of the same type
+	// as the data. Not checked if nil.
+	Want interface{}
+
+	// an error of the type expected from parsing (i.e.
+	// &opts.MissingOptionError{}), nil if parsing must succeed
+	Err error
+}
+
+// Runs each of the given cases as a subtest, parsing the case args into a new
+// struct returned by the given function and comparing the result to the case
+func Run(t *testing.T, newData func() interface{}, cases []Case, settings ...opts.Setting) {
+	t.Helper()
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.Name, func(t *testing.T) {
+			t.Helper()
+			data := newData()
+			_, err := Parse(t, data, c.Args, c.Env, settings...)
+			check(t, c, data, err)
+		})
+	}
+}
+
+// Compares the given struct and error of a parse to the given case
+func check(t testing.TB, c Case, data interface{}, err error) {
+	t.Helper()
+
+	if c.Err == nil && err != nil {
+		t.Fatalf("Cannot parse %q: %s", c.Args, err)
+		return
+	}
+
+	if c.Err != nil && reflect.TypeOf(err) != reflect.TypeOf(c.Err) {
+		t.Fatalf("Expected an error of type %T parsing %q, got %T: %v", c.Err, c.Args, err, err)
+		return
+	}
+
+	if c.Want == nil {
+		return
+	}
+
+	got := reflect.ValueOf(data)
+	want := reflect.ValueOf(c.Want)
+
+	if want.Kind() != reflect.Ptr {
+		got = got.Elem()
+	}
+
+	if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+		t.Errorf("Parsing %q\nwant: %+v\ngot:  %+v", c.Args, want.Interface(), got.Interface())
+	}
+}
@@ -0,0 +1,148 @@
+package optstest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ronelliott/go-opts"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+// Test packages using optstest often define their own -update flag for their
+// golden files, this panics if optstest defines it too
+var _ = flag.Bool("update", false, "Update the golden files of the tests")
+
+type TestOptions struct {
+	Name  string   `long:"name" short:"n" default:"foo" env:"OPTSTEST_NAME" description:"The name to use."`
+	Port  int      `long:"port" env:"OPTSTEST_PORT" required:"true" description:"The port."`
+	Files []string `positional:"true"`
+}
+
+// Records the failures of the helpers instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (this *recorder) Helper() {}
+
+func (this *recorder) Errorf(format string, args ...interface{}) {
+	this.failures = append(this.failures, fmt.Sprintf(format, args...))
+}
+
+func (this *recorder) Fatalf(format string, args ...interface{}) {
+	this.Errorf(format, args...)
+}
+
+func TestParse(t *testing.T) {
+	t.Setenv("OPTSTEST_NAME", "leaked")
+	t.Setenv("OPTSTEST_PORT", "leaked")
+
+	data := TestOptions{}
+	set, err := Parse(t, &data, []string{"a.txt"}, Env{"OPTSTEST_PORT": "80"})
+	require.Nil(t, err)
+	require.Equal(t, TestOptions{Name: "foo", Port: 80, Files: []string{"a.txt"}}, data)
+	require.Equal(t, opts.SourceEnv, set.Lookup("port").Source)
+	require.Equal(t, opts.SourceDefault, set.Lookup("name").Source)
+	require.Equal(t, "leaked", os.Getenv("OPTSTEST_PORT"))
+}
+
+func TestParse_Parallel(t *testing.T) {
+	for _, port := range []int{80, 90} {
+		port := port
+
+		t.Run(fmt.Sprint(port), func(t *testing.T) {
+			t.Parallel()
+			data := TestOptions{}
+			MustParse(t, &data, nil, Env{"OPTSTEST_PORT": fmt.Sprint(port)})
+			require.Equal(t, port, data.Port)
+		})
+	}
+}
+
+func TestEnvLookup(t *testing.T) {
+	value, ok := Env{"FOO": ""}.Lookup("FOO")
+	require.True(t, ok)
+	require.Equal(t, "", value)
+	_, ok = Env(nil).Lookup("FOO")
+	require.False(t, ok)
+}
+
+func TestParse_Error(t *testing.T) {
+	_, err := Parse(t, &TestOptions{}, nil, nil)
+	var missing *opts.MissingOptionError
+	ErrorAs(t, err, &missing)
+	require.Equal(t, "Port", missing.Option.Name)
+}
+
+func TestMustParse(t *testing.T) {
+	data := TestOptions{}
+	MustParse(t, &data, []string{"--port", "80", "-n", "bar"}, nil)
+	require.Equal(t, "bar", data.Name)
+
+	rec := recorder{TB: t}
+	MustParse(&rec, &TestOptions{}, []string{}, nil)
+	require.Equal(t, []string{"Cannot parse []: Missing required option: --port"}, rec.failures)
+}
+
+func TestHelp(t *testing.T) {
+	require.Equal(t,
+		"  -n, --name string\n    \tThe name to use. (default \"foo\")\n"+
+			"  --port int\n    \tThe port.\n",
+		Help(t, &TestOptions{}))
+
+	rec := recorder{TB: t}
+	require.Equal(t, "", Help(&rec, TestOptions{}))
+	require.Len(t, rec.failures, 1)
+}
+
+func TestErrorAs(t *testing.T) {
+	var missing *opts.MissingOptionError
+	rec := recorder{TB: t}
+	ErrorAs(&rec, nil, &missing)
+	ErrorAs(&rec, errors.New("foo"), &missing)
+	require.Equal(t, []string{
+		"Expected an error of type *opts.MissingOptionError, got nil",
+		"Expected an error of type *opts.MissingOptionError, got *errors.errorString: foo",
+	}, rec.failures)
+
+	ErrorAs(t, fmt.Errorf("wrapped: %w", &opts.MissingOptionError{}), &missing)
+}
+
+func TestGolden(t *testing.T) {
+	GoldenHelp(t, "help", &TestOptions{})
+
+	rec := recorder{TB: t}
+	Golden(&rec, "help", "other")
+	Golden(&rec, "missing", "")
+	require.Len(t, rec.failures, 2)
+}
+
+func TestRun(t *testing.T) {
+	Run(t, func() interface{} { return &TestOptions{} }, []Case{
+		{
+			Name: "flags",
+			Args: []string{"--port", "80", "a.txt"},
+			Want: TestOptions{Name: "foo", Port: 80, Files: []string{"a.txt"}},
+		},
+		{
+			Name: "env",
+			Env:  Env{"OPTSTEST_PORT": "90", "OPTSTEST_NAME": "bar"},
+			Want: &TestOptions{Name: "bar", Port: 90, Files: []string{}},
+		},
+		{
+			Name: "missing",
+			Err:  &opts.MissingOptionError{},
+		},
+	})
+}
+
+func TestCheck(t *testing.T) {
+	rec := recorder{TB: t}
+	check(&rec, Case{}, &TestOptions{}, errors.New("foo"))
+	check(&rec, Case{Err: &opts.MissingOptionError{}}, &TestOptions{}, nil)
+	check(&rec, Case{Want: TestOptions{Port: 1}}, &TestOptions{}, nil)
+	require.Len(t, rec.failures, 3)
+}
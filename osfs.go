@@ -0,0 +1,18 @@
+package opts
+
+import (
+	"io/fs"
+	"os"
+)
+
+// A file system reading files from the operating system. Unlike os.DirFS it
+// accepts any path, including absolute and relative ones.
+type osFS struct{}
+
+func (this osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (this osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
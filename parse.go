@@ -1,9 +1,10 @@
 package opts
 
 // A shortcut function for creating an OptionSet from the given struct, then
-// parses the arguments from the given args string slice.
-func Parse(data interface{}, args []string) error {
-	set, err := NewOptionSet(data)
+// parses the arguments from the given args string slice. The OptionSet is
+// configured using the given settings.
+func Parse(data interface{}, args []string, settings ...Setting) error {
+	set, err := NewOptionSet(data, settings...)
 
 	if err != nil {
 		return err
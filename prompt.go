@@ -0,0 +1,222 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Returns true if missing required options should be prompted for, which is
+// only done if prompting is enabled and the standard input is interactive
+func (this *OptionSet) canPrompt() bool {
+	return this.prompting && isTerminal(this.stdin)
+}
+
+// Returns true if the given reader is a terminal. Readers which are not files,
+// like the ones given to WithPromptIO, are treated as terminals.
+func isTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+
+	if !ok {
+		return true
+	}
+
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// Asks for the value of the given option until a valid value is given.
+// Returns a MissingOptionError if the standard input ends before that, or the
+// error reading it if it cannot be read.
+func (this *OptionSet) prompt(opt *Option) error {
+	for {
+		fmt.Fprintf(this.prompts, "%s: ", opt.promptLabel())
+
+		for n, choice := range opt.Choices {
+			if n == 0 {
+				fmt.Fprintln(this.prompts)
+			}
+
+			fmt.Fprintf(this.prompts, "  %d) %s\n", n+1, choice)
+		}
+
+		if len(opt.Choices) > 0 {
+			fmt.Fprintf(this.prompts, "Choose 1-%d: ", len(opt.Choices))
+		}
+
+		answer, err := this.readAnswer(opt.Secret)
+
+		if err == io.EOF {
+			fmt.Fprintln(this.prompts)
+			return &MissingOptionError{Option: opt}
+		} else if err != nil {
+			fmt.Fprintln(this.prompts)
+			return err
+		}
+
+		err = this.setAnswer(opt, strings.TrimSpace(answer))
+
+		if err == nil {
+			opt.Source = SourcePrompt
+			return nil
+		}
+
+		fmt.Fprintf(this.prompts, "Invalid value: %s\n", err)
+	}
+}
+
+// Reads an answer from the standard input, without echoing it if the answer
+// is secret and the standard input is a terminal. Returns io.EOF if the
+// standard input ends before the answer.
+func (this *OptionSet) readAnswer(secret bool) (string, error) {
+	file, ok := this.stdin.(*os.File)
+
+	if !secret || !ok || !isTerminal(file) {
+		return this.readLine()
+	}
+
+	// the terminal is restored by ReadPassword, even if reading fails
+	answer, err := term.ReadPassword(int(file.Fd()))
+	fmt.Fprintln(this.prompts)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(answer), nil
+}
+
+// Sets the given option to the given answer. Choices can be answered by their
+// number in the menu. The answer is parsed the same way as the flags or
+// positional args of the option.
+func (this *OptionSet) setAnswer(opt *Option, answer string) error {
+	if answer == "" {
+		return errors.New("A value is required")
+	}
+
+	if len(opt.Choices) > 0 {
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(opt.Choices) {
+			answer = opt.Choices[n-1]
+		}
+
+		if !containsString(opt.Choices, answer) {
+			return errors.New(fmt.Sprintf(
+				"Expected one of: %s",
+				strings.Join(opt.Choices, ", ")))
+		}
+	}
+
+	err := opt.checkAnswerLimits(answer)
+
+	if err != nil {
+		return err
+	}
+
+	if opt.IsPositional() {
+		return opt.store(answer)
+	}
+
+	err = this.setLiteral(opt.FlagNames()[0], answer)
+
+	if err != nil && opt.Secret {
+		return redactError(err, answer)
+	}
+
+	return err
+}
+
+// Returns the text prompting for the value of this option, i.e.
+// "The name to use (--name)"
+func (this *Option) promptLabel() string {
+	name := this.displayName()
+	description := strings.TrimRight(strings.Replace(this.Description, "`", "", -1), ".")
+
+	if description == "" {
+		return name
+	}
+
+	return description + " (" + name + ")"
+}
+
+// Returns the flag this option is best known by, i.e. "--verbose", or the
+// positional arg name for positional options
+func (this *Option) displayName() string {
+	if this.IsPositional() {
+		return this.ArgName
+	}
+
+	if this.Long != "" {
+		return formatFlagName(this.Long)
+	}
+
+	names := this.FlagNames()
+
+	if len(names) == 0 {
+		return this.Name
+	}
+
+	return formatFlagName(names[0])
+}
+
+// Prompts for, or reports, the required options which were not given
+func (this *OptionSet) checkRequired() error {
+	for _, opt := range this.order {
+		if !opt.Required || opt.IsPositional() || opt.Source != SourceDefault {
+			continue
+		}
+
+		if !this.canPrompt() {
+			return &MissingOptionError{Option: opt}
+		}
+
+		err := this.prompt(opt)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks the values of the options which were given are one of their choices
+// and within their limits
+func (this *OptionSet) checkValues() error {
+	for _, opt := range this.order {
+		if opt.Source == SourceDefault {
+			continue
+		}
+
+		err := opt.checkValue()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks this option is set to one of its choices, if it has any, and its
+// value is within its limits
+func (this *Option) checkValue() error {
+	for _, value := range this.formattedValues() {
+		if len(this.Choices) > 0 && !containsString(this.Choices, value) {
+			return errors.New(fmt.Sprintf(
+				"Invalid value '%s' for %s, expected one of: %s",
+				value,
+				this.displayName(),
+				strings.Join(this.Choices, ", ")))
+		}
+	}
+
+	if this.binding != nil {
+		return this.checkBoundLimits()
+	}
+
+	return this.checkLimits(reflect.ValueOf(this.pointer).Elem())
+}
@@ -0,0 +1,169 @@
+package opts
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type TestPromptStruct struct {
+	Name     string `long:"name" short:"n" required:"true" description:"The name to use."`
+	Password string `long:"password" required:"true" secret:"true"`
+	Level    string `long:"level" choices:"debug,info,warn" default:"info"`
+	Retries  int    `long:"retries"`
+}
+
+type TestPromptChoicesStruct struct {
+	Level string `long:"level" required:"true" choices:"debug,info,warn"`
+	Count int    `long:"count" required:"true"`
+}
+
+type TestPromptPositionalStruct struct {
+	Source string   `name:"SRC" positional:"1" required:"true"`
+	Dest   []string `name:"DST" positional:"2" required:"true"`
+}
+
+func promptTestNewSet(t *testing.T, data interface{}, in string) (*OptionSet, *bytes.Buffer) {
+	out := bytes.Buffer{}
+	set, err := NewOptionSet(data, WithPrompting(), WithPromptIO(strings.NewReader(in), &out))
+	require.Nil(t, err)
+	return set, &out
+}
+
+func TestOptionSetParse_RequiredMissing(t *testing.T) {
+	set, err := NewOptionSet(&TestPromptStruct{})
+	require.Nil(t, err)
+	err = set.Parse([]string{"--password", "secret"})
+	require.IsType(t, &MissingOptionError{}, err)
+	require.Equal(t, "Missing required option: --name", err.Error())
+}
+
+func TestOptionSetParse_RequiredGiven(t *testing.T) {
+	opts := TestPromptStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"-n", "foo", "--password", "secret"}))
+	require.Equal(t, "foo", opts.Name)
+}
+
+func TestOptionSetParse_Prompt(t *testing.T) {
+	opts := TestPromptStruct{}
+	set, out := promptTestNewSet(t, &opts, "\n  foo \nhunter2\n")
+	require.Nil(t, set.Parse([]string{"--retries", "3"}))
+	require.Equal(t, "foo", opts.Name)
+	require.Equal(t, "hunter2", opts.Password)
+	require.Equal(t, 3, opts.Retries)
+	require.Equal(t, SourcePrompt, set.Options["Name"].Source)
+	require.Equal(t, SourcePrompt, set.Options["Password"].Source)
+	require.Equal(t,
+		"The name to use (--name): Invalid value: A value is required\n"+
+			"The name to use (--name): --password: ",
+		out.String())
+}
+
+func TestOptionSetParse_PromptChoices(t *testing.T) {
+	opts := TestPromptChoicesStruct{}
+	set, out := promptTestNewSet(t, &opts, "error\n4\n3\nmany\n7\n")
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "warn", opts.Level)
+	require.Equal(t, 7, opts.Count)
+
+	menu := "--level: \n  1) debug\n  2) info\n  3) warn\nChoose 1-3: "
+	require.Equal(t,
+		menu+"Invalid value: Expected one of: debug, info, warn\n"+
+			menu+"Invalid value: Expected one of: debug, info, warn\n"+
+			menu+
+			"--count: Invalid value: parse error\n"+
+			"--count: ",
+		strings.Replace(out.String(), "invalid value \"many\" for flag -count: ", "", 1))
+}
+
+func TestOptionSetParse_PromptEOF(t *testing.T) {
+	set, _ := promptTestNewSet(t, &TestPromptStruct{}, "foo\n")
+	err := set.Parse([]string{})
+	require.IsType(t, &MissingOptionError{}, err)
+	require.Equal(t, "Password", err.(*MissingOptionError).Option.Name)
+}
+
+func TestOptionSetParse_PromptSecretDash(t *testing.T) {
+	opts := TestPromptStruct{}
+	set, _ := promptTestNewSet(t, &opts, "foo\n-\nbar\n")
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "-", opts.Password)
+}
+
+func TestOptionSetParse_PromptReadError(t *testing.T) {
+	out := bytes.Buffer{}
+	set, err := NewOptionSet(&TestPromptStruct{}, WithPrompting(), WithPromptIO(iotest.ErrReader(errors.New("device gone")), &out))
+	require.Nil(t, err)
+	require.Equal(t, "device gone", set.Parse([]string{}).Error())
+}
+
+func TestOptionSetParse_PromptPositionals(t *testing.T) {
+	opts := TestPromptPositionalStruct{}
+	set, out := promptTestNewSet(t, &opts, "a.txt\n")
+	require.Nil(t, set.Parse([]string{"src.txt"}))
+	require.Equal(t, "src.txt", opts.Source)
+	require.Equal(t, []string{"a.txt"}, opts.Dest)
+	require.Equal(t, SourcePrompt, set.Options["Dest"].Source)
+	require.Equal(t, "DST: ", out.String())
+
+	opts = TestPromptPositionalStruct{}
+	set, _ = promptTestNewSet(t, &opts, "")
+	require.IsType(t, &MissingOptionError{}, set.Parse([]string{}))
+}
+
+func TestOptionSetParse_PromptSecretRedacted(t *testing.T) {
+	data := struct {
+		Pin int `long:"pin" required:"true" secret:"true"`
+	}{}
+	set, out := promptTestNewSet(t, &data, "12a4\n1234\n")
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, 1234, data.Pin)
+	require.NotContains(t, out.String(), "12a4")
+}
+
+func TestOptionSetParse_PromptNotTerminal(t *testing.T) {
+	file, err := ioutil.TempFile(t.TempDir(), "stdin")
+	require.Nil(t, err)
+	defer file.Close()
+
+	out := bytes.Buffer{}
+	set, err := NewOptionSet(&TestPromptStruct{}, WithPrompting(), WithPromptIO(file, &out))
+	require.Nil(t, err)
+	require.IsType(t, &MissingOptionError{}, set.Parse([]string{}))
+	require.Equal(t, "", out.String())
+}
+
+func TestOptionSetParse_Choices(t *testing.T) {
+	opts := TestPromptStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"-n", "foo", "--password", "x", "--level", "warn"}))
+	require.Equal(t, "warn", opts.Level)
+
+	err = set.Parse([]string{"-n", "foo", "--password", "x", "--level", "trace"})
+	require.NotNil(t, err)
+	require.Equal(t,
+		"Invalid value 'trace' for --level, expected one of: debug, info, warn",
+		err.Error())
+}
+
+func TestIsTerminal(t *testing.T) {
+	require.True(t, isTerminal(strings.NewReader("")))
+
+	file, err := ioutil.TempFile(t.TempDir(), "stdin")
+	require.Nil(t, err)
+	defer file.Close()
+	require.False(t, isTerminal(file))
+}
+
+func TestOptionPromptLabel(t *testing.T) {
+	require.Equal(t, "--name", (&Option{Long: "name", Short: "n"}).promptLabel())
+	require.Equal(t, "The name (-n)", (&Option{Description: "The `name`.", Short: "n"}).promptLabel())
+	require.Equal(t, "SRC", (&Option{ArgName: "SRC", Position: 1, Tags: TagSet{"positional": "1"}}).promptLabel())
+}
@@ -0,0 +1,60 @@
+package opts
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+)
+
+// Restores every option to its default value, the value it had when the option
+// was added to the set, including values read from environment variables.
+// Values given as flags, positional args, read from config files or set since
+// are discarded.
+func (this *OptionSet) Reset() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	this.reset()
+	this.publish()
+}
+
+// Restores the default values of the options and replaces the flag set with a
+// new one, which has not seen any flags. Must be called while holding the
+// write lock.
+func (this *OptionSet) reset() {
+	for _, opt := range this.order {
+		if opt.binding != nil {
+			opt.binding.restore()
+			opt.Source = opt.defaultSource
+			continue
+		}
+
+		if !opt.defaultValue.IsValid() {
+			continue
+		}
+
+		value := reflect.ValueOf(opt.pointer).Elem()
+
+		// copy slices, so appending to the value does not change the default
+		if value.Kind() == reflect.Slice && !opt.defaultValue.IsNil() {
+			value.Set(reflect.AppendSlice(
+				reflect.MakeSlice(value.Type(), 0, opt.defaultValue.Len()),
+				opt.defaultValue))
+		} else {
+			value.Set(opt.defaultValue)
+		}
+
+		opt.Source = opt.defaultSource
+	}
+
+	flags := flag.NewFlagSet(this.flags.Name(), flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+
+	// the flag values store into the fields, defining them again records the
+	// restored values as their defaults
+	this.flags.VisitAll(func(f *flag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+	})
+
+	this.flags = flags
+}
@@ -0,0 +1,92 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+type TestResetStruct struct {
+	Name    string   `long:"name" default:"foo"`
+	Port    int      `long:"port" env:"OPTS_TEST_RESET_PORT"`
+	Verbose int      `short:"v" count:"true"`
+	Color   bool     `long:"color" negatable:"true" default:"true"`
+	Old     bool     `long:"old" deprecated:"true"`
+	Source  string   `name:"SRC" positional:"1"`
+	Tags    []string `positional:"2"`
+}
+
+func TestOptionSetReset(t *testing.T) {
+	os.Setenv("OPTS_TEST_RESET_PORT", "80")
+	defer os.Unsetenv("OPTS_TEST_RESET_PORT")
+
+	opts := TestResetStruct{Tags: []string{"a"}}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+	defaults := opts
+
+	require.Nil(t, set.Parse([]string{"--name", "bar", "--port", "90", "-vv", "--no-color", "src", "b", "c"}))
+	require.Equal(t, TestResetStruct{
+		Name:    "bar",
+		Port:    90,
+		Verbose: 2,
+		Source:  "src",
+		Tags:    []string{"b", "c"},
+	}, opts)
+
+	set.Reset()
+	require.Equal(t, defaults, opts)
+	require.Equal(t, SourceDefault, set.Options["Name"].Source)
+	require.Equal(t, SourceEnv, set.Options["Port"].Source)
+	require.Equal(t, SourceDefault, set.Options["Source"].Source)
+
+	// appending to the reset value does not change the default
+	opts.Tags[0] = "changed"
+	set.Reset()
+	require.Equal(t, []string{"a"}, opts.Tags)
+}
+
+func TestOptionSetParse_Reentrant(t *testing.T) {
+	warnings := bytes.Buffer{}
+	opts := TestResetStruct{}
+	set, err := NewOptionSet(&opts, WithWarningOutput(&warnings))
+	require.Nil(t, err)
+
+	require.Nil(t, set.Parse([]string{"--name", "bar", "-vv", "--old", "src"}))
+	require.Equal(t, "Flag 'old' is deprecated\n", warnings.String())
+
+	require.Nil(t, set.Parse([]string{"-v"}))
+	require.Equal(t, "foo", opts.Name)
+	require.Equal(t, 1, opts.Verbose)
+	require.False(t, opts.Old)
+	require.Equal(t, "", opts.Source)
+	require.Equal(t, SourceDefault, set.Options["Name"].Source)
+	require.Equal(t, SourceFlag, set.Options["Verbose"].Source)
+
+	// flags of earlier parses are not visited again
+	require.Equal(t, "Flag 'old' is deprecated\n", warnings.String())
+}
+
+func TestOptionSetReset_Help(t *testing.T) {
+	set, err := NewOptionSet(&TestResetStruct{})
+	require.Nil(t, err)
+
+	before := bytes.Buffer{}
+	set.WriteHelp(&before)
+	require.Nil(t, set.Parse([]string{"--name", "bar", "--no-color"}))
+	set.Reset()
+
+	after := bytes.Buffer{}
+	set.WriteHelp(&after)
+	require.Equal(t, before.String(), after.String())
+}
+
+func TestOptionSetReset_Snapshot(t *testing.T) {
+	opts := TestResetStruct{}
+	set, err := NewOptionSet(&opts, WithSnapshots())
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--name", "bar"}))
+	set.Reset()
+	require.Equal(t, "foo", set.Snapshot().(*TestResetStruct).Name)
+}
@@ -0,0 +1,200 @@
+package opts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The JSON Schema dialect of the schemas written by WriteJSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Writes a JSON Schema (draft 2020-12) of the option values to the given
+// io.Writer. The schema describes the objects written by WriteJSON and read
+// from config files, grouped options are nested objects. Each option is
+// described by its type, default, choices, limits, description and whether
+// it is deprecated. Defaults are those of the tags or fields, without the
+// values of environment variables, and secret defaults are left out.
+//
+// Options are only listed as required with the WithSchemaRequired setting, as
+// every option can also be given as a flag or positional arg, so config files
+// need not contain them. Required options are still checked by Parse, whatever
+// their source.
+//
+// Patterns are written as given, in the RE2 syntax of Go regular expressions.
+// JSON Schema validators expect ECMA-262 regular expressions, which share the
+// common syntax, but RE2-only constructs such as (?P<name>...) groups, \z or
+// (?U) flags may be rejected or read differently by them.
+func (this *OptionSet) WriteJSONSchema(out io.Writer) error {
+	data, err := json.MarshalIndent(this.jsonSchema(), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+// Returns the JSON Schema of the option values of this set
+func (this *OptionSet) jsonSchema() *dumpNode {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	root := newDumpNode()
+	root.set("$schema", JSONSchemaDialect)
+
+	if name := this.flags.Name(); name != "" {
+		root.set("title", name)
+	}
+
+	objects := map[string]*dumpNode{"": newObjectSchema(root)}
+	paths := []string{""}
+	required := map[string][]interface{}{}
+
+	for _, opt := range this.order {
+		object, ok := objects[opt.Group]
+
+		if !ok {
+			// create the schemas of the groups down to the group of the option
+			parent := ""
+
+			for _, name := range strings.Split(opt.Group, ".") {
+				path := joinPath(parent, name)
+
+				if _, ok := objects[path]; !ok {
+					objects[path] = newObjectSchema(
+						objects[parent].child("properties").child(name))
+					paths = append(paths, path)
+				}
+
+				parent = path
+			}
+
+			object = objects[opt.Group]
+		}
+
+		key := opt.dumpKey()
+		object.child("properties").set(key, opt.jsonSchema())
+
+		// positional args are not read from config files
+		if this.schemaRequired && opt.Required && !opt.IsPositional() {
+			required[opt.Group] = append(required[opt.Group], key)
+		}
+	}
+
+	for _, path := range paths {
+		if len(required[path]) > 0 {
+			objects[path].set("required", required[path])
+		}
+
+		objects[path].set("additionalProperties", false)
+	}
+
+	return root
+}
+
+// Makes the given node the schema of an object, returns the node
+func newObjectSchema(node *dumpNode) *dumpNode {
+	node.set("type", "object")
+	node.child("properties")
+	return node
+}
+
+// Returns the JSON Schema of the value of this option
+func (this *Option) jsonSchema() *dumpNode {
+	node := newDumpNode()
+	items := node
+	typ := reflect.TypeOf(this.pointer).Elem()
+
+	if typ.Kind() == reflect.Slice && !isTextType(typ) {
+		node.set("type", "array")
+		items = node.child("items")
+		typ = typ.Elem()
+	}
+
+	items.set("type", jsonSchemaType(typ))
+
+	if len(this.Choices) > 0 {
+		choices := make([]interface{}, len(this.Choices))
+
+		for n, choice := range this.Choices {
+			value := reflect.New(typ)
+			choices[n] = choice
+
+			if setScalarValue(value.Elem(), choice) == nil {
+				choices[n] = jsonScalar(value.Elem())
+			}
+		}
+
+		items.set("enum", choices)
+	}
+
+	if this.Min != "" {
+		min, _ := parseLimit(typ, this.Min)
+		items.set("minimum", jsonNumber(min))
+	}
+
+	if this.Max != "" {
+		max, _ := parseLimit(typ, this.Max)
+		items.set("maximum", jsonNumber(max))
+	}
+
+	if this.Pattern != "" {
+		items.set("pattern", this.Pattern)
+	}
+
+	if this.Description != "" {
+		node.set("description", strings.Replace(this.Description, "`", "", -1))
+	}
+
+	// the default of the tags or field, as environment variables may not be
+	// set where the config file is read
+	if this.baseDefault.IsValid() && !this.baseDefault.IsZero() && !this.Secret {
+		node.set("default", jsonValue(this.baseDefault))
+	}
+
+	if this.Deprecated != "" && len(this.DeprecatedAliases) == 0 {
+		node.set("deprecated", true)
+	}
+
+	if this.Secret {
+		node.set("writeOnly", true)
+	}
+
+	return node
+}
+
+// Returns the given parsed limit as a JSON number, integers are written
+// exactly
+func jsonNumber(limit reflect.Value) json.Number {
+	switch limit.Kind() {
+	case reflect.Int64:
+		return json.Number(strconv.FormatInt(limit.Int(), 10))
+	case reflect.Uint64:
+		return json.Number(strconv.FormatUint(limit.Uint(), 10))
+	}
+
+	return json.Number(strconv.FormatFloat(limit.Float(), 'f', -1, 64))
+}
+
+// Returns the JSON Schema type of values of the given type
+func jsonSchemaType(typ reflect.Type) string {
+	if isTextType(typ) || typ == durationType {
+		return "string"
+	}
+
+	switch {
+	case typ.Kind() == reflect.Bool:
+		return "boolean"
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		return "number"
+	case isNumberType(typ):
+		return "integer"
+	}
+
+	return "string"
+}
@@ -0,0 +1,162 @@
+package opts
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type TestSchemaStruct struct {
+	Name string `default:"app" description:"the name of the app" long:"name" pattern:"^[a-z]+$" required:"true"`
+
+	Level string `choices:"debug,info" default:"info" env:"LEVEL" long:"level"`
+
+	Database TestSchemaDatabaseStruct `group:"database"`
+
+	Password string `default:"hunter2" long:"password" secret:"true"`
+
+	Timeout time.Duration `default:"1m" long:"timeout"`
+
+	Old bool `deprecated:"use --name" long:"old"`
+
+	Ratio float64 `long:"ratio" max:"1" min:"0"`
+
+	Files []string `positional:"1" required:"true"`
+}
+
+type TestSchemaDatabaseStruct struct {
+	Port int `choices:"5432,5433" long:"db-port" max:"65535" min:"1" required:"true"`
+
+	User string `env:"DB_USER" long:"db-user" required:"true"`
+
+	Size int64 `long:"db-size" max:"9007199254740993"`
+}
+
+func TestOptionSetWriteJSONSchema(t *testing.T) {
+	set, err := NewOptionSet(&TestSchemaStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSONSchema(&buf))
+
+	expected := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "TestSchemaStruct",
+  "type": "object",
+  "properties": {
+    "name": {
+      "type": "string",
+      "pattern": "^[a-z]+$",
+      "description": "the name of the app",
+      "default": "app"
+    },
+    "level": {
+      "type": "string",
+      "enum": [
+        "debug",
+        "info"
+      ],
+      "default": "info"
+    },
+    "database": {
+      "type": "object",
+      "properties": {
+        "db-port": {
+          "type": "integer",
+          "enum": [
+            5432,
+            5433
+          ],
+          "minimum": 1,
+          "maximum": 65535
+        },
+        "db-user": {
+          "type": "string"
+        },
+        "db-size": {
+          "type": "integer",
+          "maximum": 9007199254740993
+        }
+      },
+      "additionalProperties": false
+    },
+    "password": {
+      "type": "string",
+      "writeOnly": true
+    },
+    "timeout": {
+      "type": "string",
+      "default": "1m0s"
+    },
+    "old": {
+      "type": "boolean",
+      "deprecated": true
+    },
+    "ratio": {
+      "type": "number",
+      "minimum": 0,
+      "maximum": 1
+    },
+    "Files": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    }
+  },
+  "additionalProperties": false
+}
+`
+
+	require.Equal(t, expected, buf.String())
+}
+
+func TestOptionSetWriteJSONSchema_Required(t *testing.T) {
+	set, err := NewOptionSet(&TestSchemaStruct{}, WithSchemaRequired())
+	require.Nil(t, err)
+
+	schema := map[string]interface{}{}
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSONSchema(&buf))
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &schema))
+	require.Equal(t, []interface{}{"name"}, schema["required"])
+
+	database := schema["properties"].(map[string]interface{})["database"].(map[string]interface{})
+	require.Equal(t, []interface{}{"db-port", "db-user"}, database["required"])
+}
+
+func TestOptionSetWriteJSONSchema_Env(t *testing.T) {
+	env := map[string]string{"DB_USER": "admin", "LEVEL": "debug"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	data := TestSchemaStruct{}
+	set, err := NewOptionSet(&data, WithEnv(lookup))
+	require.Nil(t, err)
+	require.Equal(t, "debug", data.Level)
+
+	schema := map[string]interface{}{}
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSONSchema(&buf))
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &schema))
+
+	properties := schema["properties"].(map[string]interface{})
+	require.Equal(t, "info", properties["level"].(map[string]interface{})["default"])
+
+	database := properties["database"].(map[string]interface{})["properties"].(map[string]interface{})
+	require.NotContains(t, database["db-user"], "default")
+}
+
+func TestOptionSetWriteJSONSchema_Valid(t *testing.T) {
+	set, err := NewOptionSet(&TestSchemaStruct{})
+	require.Nil(t, err)
+	buf := bytes.Buffer{}
+	require.Nil(t, set.WriteJSONSchema(&buf))
+
+	schema := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &schema))
+	require.Equal(t, JSONSchemaDialect, schema["$schema"])
+}
@@ -0,0 +1,215 @@
+package opts
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// A flag value for secret options, reads the value from the standard input of
+// the set when given "-"
+type secretValue struct {
+	flag.Value
+
+	// the set the standard input is read from
+	set *OptionSet
+}
+
+func (this *secretValue) Set(raw string) error {
+	if raw == "-" {
+		line, err := this.set.readStdinLine()
+
+		if err != nil {
+			return err
+		}
+
+		raw = line
+	}
+
+	return this.Value.Set(raw)
+}
+
+func (this *secretValue) String() string {
+	if this == nil || this.Value == nil {
+		return ""
+	}
+
+	return this.Value.String()
+}
+
+// A flag value reading the value of a secret option from the file given
+type secretFileValue struct {
+	// the name of the flag the value is set with
+	name string
+
+	// the set the flag belongs to
+	set *OptionSet
+}
+
+func (this *secretFileValue) Set(path string) error {
+	data, err := fs.ReadFile(this.set.fsys, path)
+
+	if err != nil {
+		return err
+	}
+
+	return this.set.flags.Set(this.name, strings.TrimRight(string(data), "\r\n"))
+}
+
+func (this *secretFileValue) String() string {
+	return ""
+}
+
+// Returns the name of the flag reading the value of this option from a file,
+// i.e. "password-file". Only secret options with a long name have one.
+func (this *Option) SecretFileFlagNames() []string {
+	if !this.Secret || this.Long == "" || this.Type == "bool" {
+		return []string{}
+	}
+
+	return []string{this.Long + "-file"}
+}
+
+// Wraps the flags of the given secret option to read values from the standard
+// input, and adds the flag reading the value from a file
+func (this *OptionSet) registerSecret(opt *Option) {
+	if opt.Type == "bool" {
+		return
+	}
+
+	for _, name := range opt.FlagNames() {
+		f := this.flags.Lookup(name)
+		f.Value = &secretValue{Value: f.Value, set: this}
+	}
+
+	for _, name := range opt.SecretFileFlagNames() {
+		this.flags.Var(
+			&secretFileValue{name: opt.Long, set: this},
+			name,
+			"Read the value of "+formatFlagName(opt.Long)+" from the given `file`.")
+	}
+}
+
+// Sets the flag with the given name to the given value, like flag.FlagSet.Set.
+// Secret values given as "-" are stored as is, not read from the standard
+// input, for values which are not given as args.
+func (this *OptionSet) setLiteral(name, raw string) error {
+	if secret, ok := this.flags.Lookup(name).Value.(*secretValue); ok {
+		return secret.Value.Set(raw)
+	}
+
+	return this.flags.Set(name, raw)
+}
+
+// Reads a single line from the standard input of this set, without the line
+// ending
+func (this *OptionSet) readStdinLine() (string, error) {
+	line, err := this.readLine()
+
+	if err != nil {
+		return "", errors.New("Cannot read secret from stdin: " + err.Error())
+	}
+
+	return line, nil
+}
+
+// Reads a single line from the standard input of this set, without the line
+// ending. Returns io.EOF if the standard input ends before the line starts.
+func (this *OptionSet) readLine() (string, error) {
+	if this.stdinReader == nil {
+		this.stdinReader = bufio.NewReader(this.stdin)
+	}
+
+	line, err := this.stdinReader.ReadString('\n')
+
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Returns the values given to secret flags in the given args
+func (this *OptionSet) secretArgs(args []string) []string {
+	secrets := []string{}
+
+	for n, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		hasValue := false
+
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value, hasValue = name[:i], name[i+1:], true
+		}
+
+		opt := this.names[name]
+
+		// the values of file flags are paths, not secrets
+		if opt == nil || !opt.Secret || !containsString(opt.FlagNames(), name) {
+			continue
+		}
+
+		if !hasValue && n+1 < len(args) {
+			value = args[n+1]
+		}
+
+		secrets = append(secrets, value)
+	}
+
+	return secrets
+}
+
+// The length secrets need to be replaced in error messages where they are not
+// quoted, shorter ones would replace unrelated text
+const minRedactLength = 4
+
+// Replaces the given secrets in the message of the given error. Secrets are
+// replaced where they are quoted, like the flag and strconv packages and this
+// package quote values (i.e. "foo" or 'foo'), and anywhere if they are long
+// enough not to match unrelated text.
+func redactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	redacted := msg
+
+	for _, secret := range secrets {
+		if secret == "" || secret == "-" {
+			continue
+		}
+
+		redacted = strings.Replace(redacted, strconv.Quote(secret), strconv.Quote(Redacted), -1)
+		redacted = strings.Replace(redacted, "'"+secret+"'", "'"+Redacted+"'", -1)
+
+		if len(secret) >= minRedactLength {
+			redacted = strings.Replace(redacted, secret, Redacted, -1)
+		}
+	}
+
+	if redacted == msg {
+		return err
+	}
+
+	return errors.New(redacted)
+}
+
+// Returns true if the given list contains the given value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package opts
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type TestSecretStruct struct {
+	Password string `long:"password" short:"p" secret:"true" description:"The password."`
+	Port     int    `long:"port" description:"The port."`
+	Token    int    `long:"token" secret:"true" description:"The token."`
+}
+
+func secretTestNewSet(t *testing.T, data *TestSecretStruct, stdin string) *OptionSet {
+	set, err := NewOptionSet(data)
+	require.Nil(t, err)
+	set.stdin = strings.NewReader(stdin)
+	return set
+}
+
+func TestOptionSecretFileFlagNames(t *testing.T) {
+	require.Equal(t, []string{"password-file"}, (&Option{Long: "password", Secret: true, Type: "string"}).SecretFileFlagNames())
+	require.Equal(t, []string{}, (&Option{Long: "password", Type: "string"}).SecretFileFlagNames())
+	require.Equal(t, []string{}, (&Option{Short: "p", Secret: true, Type: "string"}).SecretFileFlagNames())
+	require.Equal(t, []string{}, (&Option{Long: "debug", Secret: true, Type: "bool"}).SecretFileFlagNames())
+}
+
+func TestOptionSetParse_SecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.Nil(t, ioutil.WriteFile(path, []byte("hunter2\n"), 0600))
+
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "")
+	require.Nil(t, set.Parse([]string{"--password-file", path}))
+	require.Equal(t, "hunter2", data.Password)
+	require.Equal(t, SourceFlag, set.Options["Password"].Source)
+}
+
+func TestOptionSetParse_SecretFileMissing(t *testing.T) {
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "")
+	set.flags.SetOutput(ioutil.Discard)
+	require.NotNil(t, set.Parse([]string{"--password-file", filepath.Join(t.TempDir(), "missing")}))
+}
+
+func TestOptionSetParse_SecretStdin(t *testing.T) {
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "hunter2\r\n42\n")
+	require.Nil(t, set.Parse([]string{"-p", "-", "--token=-"}))
+	require.Equal(t, "hunter2", data.Password)
+	require.Equal(t, 42, data.Token)
+}
+
+func TestOptionSetParse_SecretStdinEmpty(t *testing.T) {
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "")
+	set.flags.SetOutput(ioutil.Discard)
+	err := set.Parse([]string{"--password", "-"})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Cannot read secret from stdin")
+}
+
+func TestOptionSetParse_SecretErrorRedacted(t *testing.T) {
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "")
+	set.flags.SetOutput(ioutil.Discard)
+	err := set.Parse([]string{"--token", "s3cr3t"})
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "s3cr3t")
+	require.Contains(t, err.Error(), Redacted)
+}
+
+func TestOptionSetParse_SecretStdinErrorRedacted(t *testing.T) {
+	data := TestSecretStruct{}
+	set := secretTestNewSet(t, &data, "s3cr3t\n")
+	set.flags.SetOutput(ioutil.Discard)
+	err := set.Parse([]string{"--token=-"})
+	require.NotNil(t, err)
+	require.NotContains(t, err.Error(), "s3cr3t")
+}
+
+func TestOptionSetWriteHelp_Secret(t *testing.T) {
+	os.Setenv("OPTS_TEST_SECRET", "hunter2")
+	defer os.Unsetenv("OPTS_TEST_SECRET")
+
+	data := struct {
+		Password string `long:"password" env:"OPTS_TEST_SECRET" secret:"true" description:"The password."`
+	}{}
+	set, err := NewOptionSet(&data)
+	require.Nil(t, err)
+	require.Equal(t, "hunter2", data.Password)
+
+	buf := bytes.Buffer{}
+	set.WriteHelp(&buf)
+	require.NotContains(t, buf.String(), "hunter2")
+	require.Contains(t, buf.String(), "  --password string\n    \tThe password.\n")
+	require.Contains(t, buf.String(), "  --password-file file\n    \tRead the value of --password from the given file.\n")
+}
+
+func TestOptionSet_SecretFileConflict(t *testing.T) {
+	data := struct {
+		Password     string `long:"password" secret:"true"`
+		PasswordFile string `long:"password-file"`
+	}{}
+	_, err := NewOptionSet(&data)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "password-file")
+}
+
+func TestRedactError(t *testing.T) {
+	require.Nil(t, redactError(nil, "foo"))
+	require.Equal(t, "bad ******** value", redactError(errors.New("bad hunter2 value"), "hunter2", "").Error())
+	require.Equal(t, `invalid value "********" for flag -a`, redactError(errors.New(`invalid value "a" for flag -a`), "a").Error())
+	require.Equal(t, "Invalid value '********' for --a", redactError(errors.New("Invalid value 'a' for --a"), "a").Error())
+	require.Equal(t, "bad a value", redactError(errors.New("bad a value"), "a").Error())
+
+	err := errors.New("bad value")
+	require.Equal(t, err, redactError(err, "foo", "-"))
+}
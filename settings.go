@@ -0,0 +1,156 @@
+package opts
+
+import (
+	"io"
+	"io/fs"
+)
+
+// A Setting configures an OptionSet while it is being created
+type Setting func(*OptionSet)
+
+// Expands args starting with "@" into the args listed in the file at the path
+// following it, i.e. "@build.args". Args in the file are separated by
+// whitespace and quoted like in the shell, "#" starts a comment and arg files
+// may include other arg files. Args after "--" are not expanded.
+func WithArgFiles() Setting {
+	return func(set *OptionSet) {
+		set.argFiles = true
+	}
+}
+
+// Sets the args parsed when Parse is given nil. Defaults to os.Args without
+// the program name.
+func WithArgs(args []string) Setting {
+	return func(set *OptionSet) {
+		set.args = func() []string { return args }
+	}
+}
+
+// Reads the values of options not given as flags or environment variables
+// from the JSON config file at the given path. Grouped options are read from
+// nested objects, the same way WriteJSON writes them. Can be used more than
+// once, later files override earlier ones.
+func WithConfigFile(path string) Setting {
+	return func(set *OptionSet) {
+		set.configFiles = append(set.configFiles, path)
+	}
+}
+
+// Sets the function called when a deprecated option is used. The function
+// receives the option and the flag name it was set with.
+func WithDeprecationHandler(handler func(opt *Option, name string)) Setting {
+	return func(set *OptionSet) {
+		set.onDeprecated = handler
+	}
+}
+
+// Sets the function environment variables are looked up with, instead of
+// os.LookupEnv
+func WithEnv(lookup func(name string) (string, bool)) Setting {
+	return func(set *OptionSet) {
+		set.lookupEnv = lookup
+	}
+}
+
+// Sets the file system config files, arg files and secret files are read
+// from. Defaults to the file system of the operating system, which accepts
+// any path.
+func WithFS(fsys fs.FS) Setting {
+	return func(set *OptionSet) {
+		set.fsys = fsys
+	}
+}
+
+// Derives the long names of options without a short, long or alias name from
+// their field names using the given strategy, i.e. KebabCase. Explicitly named
+// options are not changed.
+func WithNaming(strategy NamingStrategy) Setting {
+	return func(set *OptionSet) {
+		set.naming = strategy
+	}
+}
+
+// Makes every bool option with a long name negatable, unless the option is
+// tagged with negatable:"false"
+func WithNegatableBools() Setting {
+	return func(set *OptionSet) {
+		set.negatable = true
+	}
+}
+
+// Sets the writers help is printed to and warnings and prompts are written
+// to, instead of os.Stdout and os.Stderr. Settings for warnings and prompts
+// given after this one override it.
+func WithOutput(stdout, stderr io.Writer) Setting {
+	return func(set *OptionSet) {
+		set.stdout = stdout
+		set.prompts = stderr
+		set.warnings = stderr
+	}
+}
+
+// Sets the reader answers to prompts and secrets given as "-" are read from,
+// and the writer prompts are written to. Defaults to os.Stdin and os.Stderr.
+// Readers which are not files are treated as terminals, so tests can answer
+// prompts.
+func WithPromptIO(in io.Reader, out io.Writer) Setting {
+	return func(set *OptionSet) {
+		set.stdin = in
+		set.stdinReader = nil
+		set.prompts = out
+	}
+}
+
+// Prompts for the value of required options and positional args which are
+// not given, instead of failing. Options with choices are shown as a menu and
+// secret values are read without echo. Prompting is turned off when the
+// standard input is not a terminal.
+func WithPrompting() Setting {
+	return func(set *OptionSet) {
+		set.prompting = true
+	}
+}
+
+// Lists required options, other than positional args, as required properties
+// in the JSON schema written by WriteJSONSchema. Use this when the options are
+// only given in config files, which must then contain them.
+func WithSchemaRequired() Setting {
+	return func(set *OptionSet) {
+		set.schemaRequired = true
+	}
+}
+
+// Keeps a copy of the options struct, replaced after every parse and Set,
+// which is returned by Snapshot without waiting for them. Without this setting
+// Snapshot copies the struct while holding the read lock of the set.
+func WithSnapshots() Setting {
+	return func(set *OptionSet) {
+		set.snapshots = true
+	}
+}
+
+// Enables strict mode, in which tag keys not understood by this package are
+// reported as warnings
+func WithStrictTags() Setting {
+	return func(set *OptionSet) {
+		set.strict = true
+	}
+}
+
+// Reads the option tags of every field from the single tag with the given key,
+// i.e. opts:"long=name,short=n,default=foo". Fields without the tag, or with
+// the value "-", are not options. Use this to keep fields carrying other tags,
+// like json or yaml, from becoming options.
+func WithTagNamespace(key string) Setting {
+	return func(set *OptionSet) {
+		set.namespace = key
+	}
+}
+
+// Sets the writer warnings, such as the use of deprecated options, are written
+// to. Defaults to os.Stderr.
+func WithWarningOutput(out io.Writer) Setting {
+	return func(set *OptionSet) {
+		set.warnings = out
+	}
+}
@@ -0,0 +1,227 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+type TestStrictTagsStruct struct {
+	Name string `json:"name" lnog:"name" short:"n"`
+}
+
+type TestTagNamespaceStruct struct {
+	Args []string `opts:"positional"`
+
+	ID int `json:"id"`
+
+	Ignored string `json:"ignored" opts:"-"`
+
+	Name string `json:"name" opts:"long=name,short=n,default=foo"`
+
+	Verbose bool `json:"verbose" long:"verbose" opts:"short=v"`
+}
+
+type TestInvalidTagNamespaceStruct struct {
+	Name string `opts:"long=name,=n"`
+}
+
+type TestNamingStruct struct {
+	MaxConns int `default:"10"`
+
+	HTTPAddr string `description:"The address to listen on."`
+
+	Name string `short:"n"`
+
+	Color bool `default:"true"`
+}
+
+func TestWithDeprecationHandler(t *testing.T) {
+	called := false
+	set, err := NewOptionSet(&TestEmptyOptionSetStruct{},
+		WithDeprecationHandler(func(opt *Option, name string) {
+			called = true
+		}))
+	require.Nil(t, err)
+	set.warnDeprecated(&Option{Deprecated: "true"}, "foo")
+	require.True(t, called)
+}
+
+func TestWithWarningOutput(t *testing.T) {
+	buf := bytes.Buffer{}
+	set, err := NewOptionSet(&TestEmptyOptionSetStruct{}, WithWarningOutput(&buf))
+	require.Nil(t, err)
+	set.warnDeprecated(&Option{Deprecated: "true"}, "foo")
+	require.Equal(t, "Flag 'foo' is deprecated\n", buf.String())
+}
+
+func TestWithWarningOutput_Default(t *testing.T) {
+	set, err := NewOptionSet(&TestEmptyOptionSetStruct{})
+	require.Nil(t, err)
+	require.Equal(t, os.Stderr, set.warnings)
+}
+
+func TestWithNegatableBools(t *testing.T) {
+	set, err := NewOptionSet(&TestEmptyOptionSetStruct{}, WithNegatableBools())
+	require.Nil(t, err)
+	require.True(t, set.negatable)
+}
+
+func TestWithStrictTags(t *testing.T) {
+	buf := bytes.Buffer{}
+	_, err := NewOptionSet(&TestStrictTagsStruct{},
+		WithStrictTags(),
+		WithWarningOutput(&buf))
+	require.Nil(t, err)
+	require.Equal(t,
+		"Unknown tag 'json' on field Name\nUnknown tag 'lnog' on field Name\n",
+		buf.String())
+}
+
+func TestWithStrictTags_Disabled(t *testing.T) {
+	buf := bytes.Buffer{}
+	_, err := NewOptionSet(&TestStrictTagsStruct{}, WithWarningOutput(&buf))
+	require.Nil(t, err)
+	require.Equal(t, "", buf.String())
+}
+
+func TestWithTagNamespace(t *testing.T) {
+	opts := TestTagNamespaceStruct{}
+	set, err := NewOptionSet(&opts, WithTagNamespace("opts"))
+	require.Nil(t, err)
+	require.Equal(t, 3, len(set.Options))
+	require.Nil(t, set.flags.Lookup("verbose"))
+	err = set.Parse([]string{"-v", "foo"})
+	require.Nil(t, err)
+	require.Equal(t, "foo", opts.Name)
+	require.True(t, opts.Verbose)
+	require.Equal(t, []string{"foo"}, opts.Args)
+}
+
+func TestWithTagNamespace_Invalid(t *testing.T) {
+	_, err := NewOptionSet(&TestInvalidTagNamespaceStruct{},
+		WithTagNamespace("opts"))
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, "Name", err.(*TagSyntaxError).Field)
+}
+
+func TestWithTagNamespace_Disabled(t *testing.T) {
+	// without the namespace the opts tags are unknown, so only Verbose is read
+	set, err := NewOptionSet(&TestTagNamespaceStruct{})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(set.Options))
+	require.NotNil(t, set.flags.Lookup("verbose"))
+}
+
+func TestWithNaming(t *testing.T) {
+	opts := TestNamingStruct{}
+	set, err := NewOptionSet(&opts, WithNaming(KebabCase))
+	require.Nil(t, err)
+	require.Equal(t, "max-conns", set.Options["MaxConns"].Long)
+	require.Equal(t, "http-addr", set.Options["HTTPAddr"].Long)
+	require.Equal(t, "", set.Options["Name"].Long)
+	err = set.Parse([]string{"--max-conns", "20", "--http-addr", ":80", "-n", "foo"})
+	require.Nil(t, err)
+	require.Equal(t, 20, opts.MaxConns)
+	require.Equal(t, ":80", opts.HTTPAddr)
+	require.Equal(t, "foo", opts.Name)
+}
+
+func TestWithNaming_Custom(t *testing.T) {
+	set, err := NewOptionSet(&TestNamingStruct{},
+		WithNaming(func(field string) string {
+			return "x-" + SnakeCase(field)
+		}))
+	require.Nil(t, err)
+	require.Equal(t, "x-max_conns", set.Options["MaxConns"].Long)
+}
+
+func TestWithNaming_Negatable(t *testing.T) {
+	opts := TestNamingStruct{}
+	set, err := NewOptionSet(&opts, WithNaming(KebabCase), WithNegatableBools())
+	require.Nil(t, err)
+	err = set.Parse([]string{"--no-color"})
+	require.Nil(t, err)
+	require.False(t, opts.Color)
+}
+
+type TestInjectStruct struct {
+	Name     string `long:"name" env:"NAME" default:"foo"`
+	Port     int    `long:"port" env:"PORT"`
+	Password string `long:"password" secret:"true"`
+	Old      bool   `long:"old" deprecated:"true"`
+}
+
+func TestWithArgs(t *testing.T) {
+	opts := TestInjectStruct{}
+	set, err := NewOptionSet(&opts, WithArgs([]string{"--name", "bar"}))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse(nil))
+	require.Equal(t, "bar", opts.Name)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "foo", opts.Name)
+}
+
+func TestWithEnv(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{"NAME": "bar", "PORT": "80"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	opts := TestInjectStruct{}
+	set, err := NewOptionSet(&opts, WithEnv(lookup))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "bar", opts.Name)
+	require.Equal(t, 80, opts.Port)
+	require.Equal(t, SourceEnv, set.Options["Port"].Source)
+
+	port := 0
+	_, err = set.AddOption(Option{Long: "other-port", Env: "PORT"}, &port)
+	require.Nil(t, err)
+	require.Equal(t, 80, port)
+
+	env["PORT"] = "90"
+	watcher := set.Watch(0)
+	defer watcher.Close()
+	_, err = watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, 90, watcher.Current().(*TestInjectStruct).Port)
+}
+
+func TestWithFS(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"etc/app.json":     {Data: []byte(`{"name": "bar"}`)},
+		"args/build.args":  {Data: []byte("--port 80\n@common.args\n")},
+		"args/common.args": {Data: []byte("--password-file secrets/password\n")},
+		"secrets/password": {Data: []byte("hunter2\n")},
+	}
+
+	opts := TestInjectStruct{}
+	set, err := NewOptionSet(&opts, WithFS(fsys), WithConfigFile("etc/app.json"), WithArgFiles(), WithEnv(func(string) (string, bool) { return "", false }))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"@args/build.args"}))
+	require.Equal(t, "bar", opts.Name)
+	require.Equal(t, 80, opts.Port)
+	require.Equal(t, "hunter2", opts.Password)
+}
+
+func TestWithOutput(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	set, err := NewOptionSet(&TestInjectStruct{}, WithOutput(&stdout, &stderr))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{"--old"}))
+	require.Equal(t, "Flag 'old' is deprecated\n", stderr.String())
+
+	set.PrintHelp()
+	help := bytes.Buffer{}
+	set.WriteHelp(&help)
+	require.Equal(t, help.String(), stdout.String())
+}
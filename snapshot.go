@@ -0,0 +1,43 @@
+package opts
+
+import "reflect"
+
+// Returns a pointer to a copy of the options struct of this set, nil for sets
+// created without a struct. The copy is not changed by later parses, so it
+// can be read while they run. Read option values through snapshots, or the
+// other methods of the set, when parsing concurrently. Reloaded values are
+// read through Watcher.Current.
+func (this *OptionSet) Snapshot() interface{} {
+	if this.snapshots {
+		return this.snapshot.Load()
+	}
+
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.copyData()
+}
+
+// Stores a copy of the options struct as the snapshot of this set, if
+// snapshots are kept. Must be called while holding the write lock.
+func (this *OptionSet) publish() {
+	if !this.snapshots {
+		return
+	}
+
+	if data := this.copyData(); data != nil {
+		this.snapshot.Store(data)
+	}
+}
+
+// Returns a pointer to a copy of the options struct of this set, nil for sets
+// created without a struct. Slices are copied, so the copy does not change
+// when the options are changed.
+func (this *OptionSet) copyData() interface{} {
+	if !this.data.IsValid() {
+		return nil
+	}
+
+	data := reflect.New(this.data.Type())
+	data.Elem().Set(cloneValue(this.data))
+	return data.Interface()
+}
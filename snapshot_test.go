@@ -0,0 +1,151 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type TestSnapshotStruct struct {
+	Name  string `long:"name" default:"foo"`
+	Count int    `long:"count"`
+}
+
+type TestSnapshotSliceStruct struct {
+	Files []string `positional:"true"`
+}
+
+func TestOptionSetSnapshot(t *testing.T) {
+	opts := TestSnapshotStruct{}
+	set, err := NewOptionSet(&opts)
+	require.Nil(t, err)
+
+	snapshot := set.Snapshot().(*TestSnapshotStruct)
+	require.Equal(t, TestSnapshotStruct{Name: "foo"}, *snapshot)
+
+	require.Nil(t, set.Parse([]string{"--name", "bar", "--count", "2"}))
+	require.Equal(t, TestSnapshotStruct{Name: "foo"}, *snapshot)
+	require.Equal(t, TestSnapshotStruct{Name: "bar", Count: 2}, *set.Snapshot().(*TestSnapshotStruct))
+	require.False(t, set.Snapshot() == set.Snapshot())
+}
+
+func TestOptionSetSnapshot_Kept(t *testing.T) {
+	opts := TestSnapshotStruct{}
+	set, err := NewOptionSet(&opts, WithSnapshots())
+	require.Nil(t, err)
+	require.Equal(t, TestSnapshotStruct{Name: "foo"}, *set.Snapshot().(*TestSnapshotStruct))
+
+	require.Nil(t, set.Parse([]string{"--count", "3"}))
+	snapshot := set.Snapshot().(*TestSnapshotStruct)
+	require.Equal(t, TestSnapshotStruct{Name: "foo", Count: 3}, *snapshot)
+	require.True(t, snapshot == set.Snapshot())
+
+	// failed parses keep the last snapshot
+	require.NotNil(t, set.Parse([]string{"--count", "x"}))
+	require.True(t, snapshot == set.Snapshot())
+}
+
+func TestOptionSetSnapshot_Slices(t *testing.T) {
+	for _, settings := range [][]Setting{{}, {WithSnapshots()}} {
+		opts := TestSnapshotSliceStruct{}
+		set, err := NewOptionSet(&opts, settings...)
+		require.Nil(t, err)
+		require.Nil(t, set.Parse([]string{"a", "b"}))
+
+		snapshot := set.Snapshot().(*TestSnapshotSliceStruct)
+		opts.Files[0] = "changed"
+		require.Equal(t, []string{"a", "b"}, snapshot.Files)
+	}
+}
+
+func TestOptionSetSnapshot_Empty(t *testing.T) {
+	require.Nil(t, NewEmptyOptionSet("empty").Snapshot())
+	require.Nil(t, NewEmptyOptionSet("empty", WithSnapshots()).Snapshot())
+}
+
+func TestOptionSetSnapshot_ConcurrentParse(t *testing.T) {
+	for _, settings := range [][]Setting{{}, {WithSnapshots()}} {
+		opts := TestSnapshotStruct{}
+		set, err := NewOptionSet(&opts, settings...)
+		require.Nil(t, err)
+
+		wait := sync.WaitGroup{}
+
+		for n := 0; n < 4; n++ {
+			wait.Add(2)
+
+			go func(n int) {
+				defer wait.Done()
+
+				for i := 0; i < 50; i++ {
+					count := strconv.Itoa(n*100 + i)
+					assert.Nil(t, set.Parse([]string{"--name", "n" + count, "--count", count}))
+				}
+			}(n)
+
+			go func() {
+				defer wait.Done()
+
+				for i := 0; i < 50; i++ {
+					snapshot := set.Snapshot().(*TestSnapshotStruct)
+
+					// parses never show half written values
+					if snapshot.Count != 0 {
+						assert.Equal(t, "n"+strconv.Itoa(snapshot.Count), snapshot.Name)
+					}
+
+					assert.Nil(t, set.WriteJSON(&bytes.Buffer{}))
+					set.WriteHelp(&bytes.Buffer{})
+					set.Args()
+				}
+			}()
+		}
+
+		wait.Wait()
+	}
+}
+
+func TestOptionSetSnapshot_ConcurrentReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.Nil(t, ioutil.WriteFile(path, []byte(`{"count": 1}`), 0644))
+
+	opts := TestSnapshotStruct{}
+	set, err := NewOptionSet(&opts, WithConfigFile(path), WithSnapshots())
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	wait := sync.WaitGroup{}
+	wait.Add(2)
+
+	go func() {
+		defer wait.Done()
+
+		for i := 2; i < 50; i++ {
+			data := `{"count": ` + strconv.Itoa(i) + `}`
+			assert.Nil(t, ioutil.WriteFile(path, []byte(data), 0644))
+			_, err := watcher.Reload()
+			assert.Nil(t, err)
+		}
+	}()
+
+	go func() {
+		defer wait.Done()
+
+		for i := 0; i < 50; i++ {
+			assert.Equal(t, "foo", watcher.Current().(*TestSnapshotStruct).Name)
+			assert.Nil(t, set.WriteEnv(&bytes.Buffer{}))
+		}
+	}()
+
+	wait.Wait()
+	require.Equal(t, 49, watcher.Current().(*TestSnapshotStruct).Count)
+	require.Equal(t, 1, set.Snapshot().(*TestSnapshotStruct).Count)
+}
@@ -0,0 +1,529 @@
+package opts
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The binding of an option to its field, which parses, stores and formats
+// the values of the field without reflection. Created by Bind, BindText and
+// BindTexts, used by the parsers generated by "optsgen --parser".
+type Binding interface {
+	// empties the field of slices, keeping room for the given number of
+	// values
+	clear(size int)
+
+	// returns the kind of the elements of slices, the kind of the field
+	// otherwise
+	elemKind() reflect.Kind
+
+	// returns the kind of the field
+	kind() reflect.Kind
+
+	// parses the given raw value, returning it formatted like formatValue
+	parse(raw string) (string, error)
+
+	// returns the pointer to the field
+	pointer() interface{}
+
+	// restores the value of the field stored by save
+	restore()
+
+	// stores the current value of the field, restored by restore
+	save()
+
+	// parses the given raw value and stores it in the field, appending it to
+	// slices
+	set(raw string) error
+
+	// returns true if the field is a slice, storing every value it is set to
+	slice() bool
+
+	// returns the type of the field (i.e. "[]string")
+	typ() string
+
+	// returns the current value of the field formatted like formatValue, one
+	// string for each element of slices
+	values() []string
+}
+
+// The binding of a field holding a single value
+type scalarBinding[T any] struct {
+	// the kind of the field
+	fieldKind reflect.Kind
+
+	// the type of the field
+	fieldType string
+
+	// parses the values of the field
+	parseValue func(raw string) (T, error)
+
+	// the value stored by save
+	saved T
+
+	// the pointer to the field
+	target *T
+}
+
+// The binding of a slice field, storing every value it is set to
+type sliceBinding[T any] struct {
+	// the kind of the elements of the field
+	fieldElemKind reflect.Kind
+
+	// the type of the field
+	fieldType string
+
+	// parses the elements of the field
+	parseValue func(raw string) (T, error)
+
+	// the value stored by save
+	saved []T
+
+	// the pointer to the field
+	target *[]T
+}
+
+// A pointer to a value of type T stored as text
+type textPointer[T any] interface {
+	*T
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+}
+
+// Creates a new OptionSet with the given name from the given options of the
+// fields of an options struct, as read from their tags by "optsgen --parser",
+// configured using the given settings. Each option is stored in the field of
+// the binding at the same index. The set parses args the same way as one
+// created by NewOptionSet for the struct, but neither creating it nor parsing
+// reflects on the struct or parses its tags. The options are copied, their
+// Kind, Source and Type are set from their bindings. The tag namespace setting
+// has no effect, as the tags are already read. Snapshot returns nil and Watch
+// does not reload values for static sets, as both copy the struct.
+func NewStaticOptionSet(name string, options []Option, bindings []Binding, settings ...Setting) (*OptionSet, error) {
+	if len(bindings) != len(options) {
+		return nil, errors.New("Expected a binding for each option.")
+	}
+
+	set := NewEmptyOptionSet(name, settings...)
+	defined := make([]*Option, len(options))
+
+	for n, binding := range bindings {
+		opt := options[n]
+		opt.Aliases = append([]string{}, opt.Aliases...)
+		opt.Choices = append([]string{}, opt.Choices...)
+		opt.DeprecatedAliases = append([]string{}, opt.DeprecatedAliases...)
+		opt.Kind = binding.kind()
+		opt.Source = SourceDefault
+		opt.Tags = copyTags(opt.Tags)
+		opt.Type = binding.typ()
+		opt.binding = binding
+		opt.bind(binding.pointer(), set.lookupEnv)
+
+		err := opt.compilePattern()
+
+		if err != nil {
+			return nil, err
+		}
+
+		set.place(&opt, "", opt.Group)
+		defined[n] = &opt
+	}
+
+	err := set.addOptions(defined)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Binds an option to the field the given pointer points to. The field must be
+// a bool, number, string or time.Duration, or a slice of those. Fields of
+// other types panic, use BindText or BindTexts for types stored as text.
+func Bind(target interface{}) Binding {
+	switch target := target.(type) {
+	case *bool:
+		return bindScalar(target, "bool", reflect.Bool, strconv.ParseBool)
+	case *[]bool:
+		return bindSlice(target, "[]bool", reflect.Bool, strconv.ParseBool)
+	case *float32:
+		return bindScalar(target, "float32", reflect.Float32, parseFloat32)
+	case *[]float32:
+		return bindSlice(target, "[]float32", reflect.Float32, parseFloat32)
+	case *float64:
+		return bindScalar(target, "float64", reflect.Float64, parseFloat64)
+	case *[]float64:
+		return bindSlice(target, "[]float64", reflect.Float64, parseFloat64)
+	case *int:
+		return bindScalar(target, "int", reflect.Int, parseInt)
+	case *[]int:
+		return bindSlice(target, "[]int", reflect.Int, parseInt)
+	case *int8:
+		return bindScalar(target, "int8", reflect.Int8, parseInt8)
+	case *[]int8:
+		return bindSlice(target, "[]int8", reflect.Int8, parseInt8)
+	case *int16:
+		return bindScalar(target, "int16", reflect.Int16, parseInt16)
+	case *[]int16:
+		return bindSlice(target, "[]int16", reflect.Int16, parseInt16)
+	case *int32:
+		return bindScalar(target, "int32", reflect.Int32, parseInt32)
+	case *[]int32:
+		return bindSlice(target, "[]int32", reflect.Int32, parseInt32)
+	case *int64:
+		return bindScalar(target, "int64", reflect.Int64, parseInt64)
+	case *[]int64:
+		return bindSlice(target, "[]int64", reflect.Int64, parseInt64)
+	case *string:
+		return bindScalar(target, "string", reflect.String, parseString)
+	case *[]string:
+		return bindSlice(target, "[]string", reflect.String, parseString)
+	case *time.Duration:
+		return bindScalar(target, "time.Duration", reflect.Int64, time.ParseDuration)
+	case *[]time.Duration:
+		return bindSlice(target, "[]time.Duration", reflect.Int64, time.ParseDuration)
+	case *uint:
+		return bindScalar(target, "uint", reflect.Uint, parseUint)
+	case *[]uint:
+		return bindSlice(target, "[]uint", reflect.Uint, parseUint)
+	case *uint8:
+		return bindScalar(target, "uint8", reflect.Uint8, parseUint8)
+	case *[]uint8:
+		return bindSlice(target, "[]uint8", reflect.Uint8, parseUint8)
+	case *uint16:
+		return bindScalar(target, "uint16", reflect.Uint16, parseUint16)
+	case *[]uint16:
+		return bindSlice(target, "[]uint16", reflect.Uint16, parseUint16)
+	case *uint32:
+		return bindScalar(target, "uint32", reflect.Uint32, parseUint32)
+	case *[]uint32:
+		return bindSlice(target, "[]uint32", reflect.Uint32, parseUint32)
+	case *uint64:
+		return bindScalar(target, "uint64", reflect.Uint64, parseUint64)
+	case *[]uint64:
+		return bindSlice(target, "[]uint64", reflect.Uint64, parseUint64)
+	}
+
+	panic(fmt.Sprintf("Cannot bind field of type %T", target))
+}
+
+// Binds an option to the field the given pointer points to, of the given
+// type (i.e. "net.IP") stored as text. The kind is the kind of the type.
+func BindText[T any, P textPointer[T]](target P, typ string, kind reflect.Kind) Binding {
+	return bindScalar((*T)(target), typ, kind, parseText[T, P])
+}
+
+// Binds an option to the slice the given pointer points to, of the given
+// type (i.e. "[]net.IP") with elements stored as text
+func BindTexts[T any, P textPointer[T]](target *[]T, typ string) Binding {
+	return bindSlice(target, typ, reflect.Invalid, parseText[T, P])
+}
+
+// Binds an option to the given scalar field of the given type and kind,
+// parsing its values with the given function
+func bindScalar[T any](target *T, typ string, kind reflect.Kind, parse func(raw string) (T, error)) Binding {
+	return &scalarBinding[T]{
+		fieldKind:  kind,
+		fieldType:  typ,
+		parseValue: parse,
+		target:     target,
+	}
+}
+
+// Binds an option to the given slice field of the given type, with elements
+// of the given kind, parsing its elements with the given function
+func bindSlice[T any](target *[]T, typ string, kind reflect.Kind, parse func(raw string) (T, error)) Binding {
+	return &sliceBinding[T]{
+		fieldElemKind: kind,
+		fieldType:     typ,
+		parseValue:    parse,
+		target:        target,
+	}
+}
+
+func (this *scalarBinding[T]) clear(size int) {}
+
+func (this *scalarBinding[T]) elemKind() reflect.Kind {
+	return this.fieldKind
+}
+
+func (this *scalarBinding[T]) kind() reflect.Kind {
+	return this.fieldKind
+}
+
+func (this *scalarBinding[T]) parse(raw string) (string, error) {
+	value, err := this.parseValue(raw)
+
+	if err != nil {
+		return "", err
+	}
+
+	return formatBound(&value), nil
+}
+
+func (this *scalarBinding[T]) pointer() interface{} {
+	return this.target
+}
+
+func (this *scalarBinding[T]) restore() {
+	*this.target = this.saved
+}
+
+func (this *scalarBinding[T]) save() {
+	this.saved = *this.target
+}
+
+func (this *scalarBinding[T]) set(raw string) error {
+	value, err := this.parseValue(raw)
+
+	if err != nil {
+		return err
+	}
+
+	*this.target = value
+	return nil
+}
+
+func (this *scalarBinding[T]) slice() bool {
+	return false
+}
+
+func (this *scalarBinding[T]) typ() string {
+	return this.fieldType
+}
+
+func (this *scalarBinding[T]) values() []string {
+	return []string{formatBound(this.target)}
+}
+
+func (this *sliceBinding[T]) clear(size int) {
+	*this.target = make([]T, 0, size)
+}
+
+func (this *sliceBinding[T]) elemKind() reflect.Kind {
+	return this.fieldElemKind
+}
+
+func (this *sliceBinding[T]) kind() reflect.Kind {
+	return reflect.Slice
+}
+
+func (this *sliceBinding[T]) parse(raw string) (string, error) {
+	value, err := this.parseValue(raw)
+
+	if err != nil {
+		return "", err
+	}
+
+	return formatBound(&value), nil
+}
+
+func (this *sliceBinding[T]) pointer() interface{} {
+	return this.target
+}
+
+// Restores a copy of the saved value, so appending to the field does not
+// change it
+func (this *sliceBinding[T]) restore() {
+	*this.target = copySlice(this.saved)
+}
+
+func (this *sliceBinding[T]) save() {
+	this.saved = copySlice(*this.target)
+}
+
+func (this *sliceBinding[T]) set(raw string) error {
+	value, err := this.parseValue(raw)
+
+	if err != nil {
+		return err
+	}
+
+	*this.target = append(*this.target, value)
+	return nil
+}
+
+func (this *sliceBinding[T]) slice() bool {
+	return true
+}
+
+func (this *sliceBinding[T]) typ() string {
+	return this.fieldType
+}
+
+func (this *sliceBinding[T]) values() []string {
+	values := make([]string, len(*this.target))
+
+	for n := range values {
+		values[n] = formatBound(&(*this.target)[n])
+	}
+
+	return values
+}
+
+// Formats the value the given pointer points to like formatValue
+func formatBound[T any](value *T) string {
+	if marshaler, ok := interface{}(value).(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	return fmt.Sprint(*value)
+}
+
+// Returns a copy of the given slice, nil if the slice is nil
+func copySlice[T any](values []T) []T {
+	if values == nil {
+		return nil
+	}
+
+	return append(make([]T, 0, len(values)), values...)
+}
+
+// Parses a value stored as text
+func parseText[T any, P textPointer[T]](raw string) (T, error) {
+	var value T
+	err := P(&value).UnmarshalText([]byte(raw))
+	return value, err
+}
+
+func parseFloat32(raw string) (float32, error) {
+	value, err := strconv.ParseFloat(raw, 32)
+	return float32(value), err
+}
+
+func parseFloat64(raw string) (float64, error) {
+	return strconv.ParseFloat(raw, 64)
+}
+
+func parseInt(raw string) (int, error) {
+	value, err := strconv.ParseInt(raw, 0, strconv.IntSize)
+	return int(value), err
+}
+
+func parseInt8(raw string) (int8, error) {
+	value, err := strconv.ParseInt(raw, 0, 8)
+	return int8(value), err
+}
+
+func parseInt16(raw string) (int16, error) {
+	value, err := strconv.ParseInt(raw, 0, 16)
+	return int16(value), err
+}
+
+func parseInt32(raw string) (int32, error) {
+	value, err := strconv.ParseInt(raw, 0, 32)
+	return int32(value), err
+}
+
+func parseInt64(raw string) (int64, error) {
+	return strconv.ParseInt(raw, 0, 64)
+}
+
+func parseString(raw string) (string, error) {
+	return raw, nil
+}
+
+func parseUint(raw string) (uint, error) {
+	value, err := strconv.ParseUint(raw, 0, strconv.IntSize)
+	return uint(value), err
+}
+
+func parseUint8(raw string) (uint8, error) {
+	value, err := strconv.ParseUint(raw, 0, 8)
+	return uint8(value), err
+}
+
+func parseUint16(raw string) (uint16, error) {
+	value, err := strconv.ParseUint(raw, 0, 16)
+	return uint16(value), err
+}
+
+func parseUint32(raw string) (uint32, error) {
+	value, err := strconv.ParseUint(raw, 0, 32)
+	return uint32(value), err
+}
+
+func parseUint64(raw string) (uint64, error) {
+	return strconv.ParseUint(raw, 0, 64)
+}
+
+// Checks the current value of this option, bound without reflection, is
+// within its limits, like checkLimits
+func (this *Option) checkBoundLimits() error {
+	if this.Min == "" && this.Max == "" && this.pattern == nil {
+		return nil
+	}
+
+	for _, value := range this.binding.values() {
+		if problem := this.boundLimitProblem(value); problem != "" {
+			return this.limitError(value, problem)
+		}
+	}
+
+	return nil
+}
+
+// Returns how the given formatted value of this option, bound without
+// reflection, is not within its limits, like limitProblem. Returns an empty
+// problem if the value is within the limits.
+func (this *Option) boundLimitProblem(value string) string {
+	if this.pattern != nil {
+		if !this.pattern.MatchString(value) {
+			return "expected a value matching " + this.Pattern
+		}
+
+		return ""
+	}
+
+	kind := this.binding.elemKind()
+
+	if this.Min != "" && compareLimit(kind, value, this.Min) < 0 {
+		return "expected at least " + this.Min
+	}
+
+	if this.Max != "" && compareLimit(kind, value, this.Max) > 0 {
+		return "expected at most " + this.Max
+	}
+
+	return ""
+}
+
+// Compares the given formatted number of the given kind to the given limit.
+// Returns -1 if the number is less than the limit, 1 if it is greater and 0
+// if they are equal.
+func compareLimit(kind reflect.Kind, value, limit string) int {
+	switch {
+	case isIntKind(kind):
+		a, _ := strconv.ParseInt(value, 10, 64)
+		b, _ := strconv.ParseInt(limit, 10, 64)
+
+		if a != b {
+			return compareOrder(a < b)
+		}
+
+	case isUintKind(kind):
+		a, _ := strconv.ParseUint(value, 10, 64)
+		b, _ := strconv.ParseUint(limit, 10, 64)
+
+		if a != b {
+			return compareOrder(a < b)
+		}
+
+	default:
+		a, _ := strconv.ParseFloat(value, 64)
+		b, _ := strconv.ParseFloat(limit, 64)
+
+		if a != b {
+			return compareOrder(a < b)
+		}
+	}
+
+	return 0
+}
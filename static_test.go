@@ -0,0 +1,205 @@
+package opts
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type TestStaticStruct struct {
+	Name     string                   `long:"name" short:"n" default:"foo" env:"NAME"`
+	Timeout  time.Duration            `long:"timeout" default:"1s"`
+	Addr     net.IP                   `positional:"1" name:"ADDR"`
+	Files    []string                 `positional:"true"`
+	Database TestStaticDatabaseStruct `group:"database"`
+}
+
+type TestStaticDatabaseStruct struct {
+	Host string `long:"db-host" colour:"red"`
+}
+
+// The options of TestStaticStruct, as generated by "optsgen --parser"
+var testStaticOptions = []Option{
+	{ArgName: "NAME", Default: "foo", Env: "NAME", Long: "name", Name: "Name", Short: "n", Tags: TagSet{"default": "foo", "env": "NAME", "long": "name", "short": "n"}},
+	{ArgName: "TIMEOUT", Default: "1s", Long: "timeout", Name: "Timeout", Tags: TagSet{"default": "1s", "long": "timeout"}},
+	{ArgName: "ADDR", Name: "Addr", Position: 1, Tags: TagSet{"name": "ADDR", "positional": "1"}},
+	{ArgName: "FILES", Name: "Files", Tags: TagSet{"positional": "true"}},
+	{ArgName: "HOST", Group: "database", Long: "db-host", Name: "Database.Host", Tags: TagSet{"colour": "red", "long": "db-host"}},
+}
+
+func testStaticBindings(data *TestStaticStruct) []Binding {
+	return []Binding{
+		Bind(&data.Name),
+		Bind(&data.Timeout),
+		BindText(&data.Addr, "net.IP", reflect.Slice),
+		Bind(&data.Files),
+		Bind(&data.Database.Host),
+	}
+}
+
+func TestNewStaticOptionSet(t *testing.T) {
+	data := TestStaticStruct{}
+	env := func(name string) (string, bool) { return "bar", name == "NAME" }
+	set, err := NewStaticOptionSet("TestStaticStruct", testStaticOptions, testStaticBindings(&data), WithEnv(env))
+	require.Nil(t, err)
+	require.Equal(t, TestStaticStruct{Name: "bar", Timeout: time.Second}, data)
+
+	require.Nil(t, set.Parse([]string{"--timeout", "2s", "--db-host", "db", "10.0.0.1", "a", "b"}))
+	require.Equal(t, TestStaticStruct{
+		Name:     "bar",
+		Timeout:  2 * time.Second,
+		Addr:     net.ParseIP("10.0.0.1"),
+		Files:    []string{"a", "b"},
+		Database: TestStaticDatabaseStruct{Host: "db"},
+	}, data)
+
+	host := set.Lookup("db-host")
+	require.Equal(t, "database", host.Group)
+	require.Equal(t, reflect.String, host.Kind)
+	require.Equal(t, "string", host.Type)
+	require.Equal(t, SourceFlag, host.Source)
+	require.Equal(t, "time.Duration", set.Lookup("Timeout").Type)
+	require.Equal(t, "net.IP", set.Lookup("Addr").Type)
+	require.Equal(t, reflect.Slice, set.Lookup("Files").Kind)
+	require.Equal(t, SourceEnv, set.Lookup("name").Source)
+	require.Equal(t, "[options] [ADDR] [FILES...]", set.Usage())
+	require.Nil(t, set.Snapshot())
+
+	set.Reset()
+	require.Equal(t, TestStaticStruct{Name: "bar", Timeout: time.Second}, data)
+	require.Equal(t, SourceDefault, host.Source)
+}
+
+func TestNewStaticOptionSet_Settings(t *testing.T) {
+	data := TestStaticStruct{}
+	warnings := bytes.Buffer{}
+	_, err := NewStaticOptionSet("TestStaticStruct", testStaticOptions, testStaticBindings(&data), WithStrictTags(), WithWarningOutput(&warnings))
+	require.Nil(t, err)
+	require.Equal(t, "Unknown tag 'colour' on field Database.Host\n", warnings.String())
+}
+
+func TestNewStaticOptionSet_Limits(t *testing.T) {
+	data := struct {
+		Count int
+		Name  string
+		Sizes []uint
+	}{}
+
+	set, err := NewStaticOptionSet("Limits", []Option{
+		{Long: "count", Max: "3", Min: "1", Name: "Count", Tags: TagSet{}},
+		{Long: "name", Name: "Name", Pattern: "^[a-z]+$", Tags: TagSet{}},
+		{ArgName: "SIZES", Max: "10", Name: "Sizes", Tags: TagSet{"positional": "true"}},
+	}, []Binding{
+		Bind(&data.Count),
+		Bind(&data.Name),
+		Bind(&data.Sizes),
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, set.Parse([]string{"--count", "3", "--name", "abc", "1", "10"}))
+	require.Equal(t, []uint{1, 10}, data.Sizes)
+	require.Equal(t, "Invalid value '4' for --count, expected at most 3", set.Parse([]string{"--count", "4"}).Error())
+	require.Equal(t, "Invalid value 'ABC' for --name, expected a value matching ^[a-z]+$", set.Parse([]string{"--name", "ABC"}).Error())
+	require.Equal(t, "Invalid value '11' for SIZES, expected at most 10", set.Parse([]string{"1", "11"}).Error())
+}
+
+func TestNewStaticOptionSet_Errors(t *testing.T) {
+	data := TestStaticStruct{}
+
+	_, err := NewStaticOptionSet("TestStaticStruct", []Option{
+		{Long: "name", Name: "Name", Tags: TagSet{}},
+		{Long: "name", Name: "Host", Tags: TagSet{}},
+	}, []Binding{
+		Bind(&data.Name),
+		Bind(&data.Database.Host),
+	})
+	require.Equal(t, "Invalid option definitions:\n  Flag 'name' is defined by options 'Name' and 'Host'", err.Error())
+
+	_, err = NewStaticOptionSet("TestStaticStruct", []Option{
+		{Default: "soon", Long: "timeout", Name: "Timeout", Tags: TagSet{}},
+	}, []Binding{
+		Bind(&data.Timeout),
+	})
+	require.NotNil(t, err)
+
+	_, err = NewStaticOptionSet("TestStaticStruct", []Option{
+		{Long: "name", Name: "Name", Pattern: "(", Tags: TagSet{}},
+	}, []Binding{
+		Bind(&data.Name),
+	})
+	require.Equal(t, "Invalid pattern for option Name: error parsing regexp: missing closing ): `(`", err.Error())
+	_, err = NewStaticOptionSet("TestStaticStruct", testStaticOptions, nil)
+	require.Equal(t, "Expected a binding for each option.", err.Error())
+}
+
+func TestBind(t *testing.T) {
+	var count int8
+	var durations []time.Duration
+
+	binding := Bind(&count)
+	require.Equal(t, "int8", binding.typ())
+	require.Equal(t, reflect.Int8, binding.kind())
+	require.Nil(t, binding.set("0x10"))
+	require.Equal(t, int8(16), count)
+	require.Equal(t, []string{"16"}, binding.values())
+	require.Equal(t, `strconv.ParseInt: parsing "300": value out of range`, binding.set("300").Error())
+
+	binding = Bind(&durations)
+	require.Equal(t, "[]time.Duration", binding.typ())
+	require.Equal(t, reflect.Slice, binding.kind())
+	require.Equal(t, reflect.Int64, binding.elemKind())
+	require.True(t, binding.slice())
+	require.Nil(t, binding.set("1s"))
+	require.Nil(t, binding.set("1m"))
+	require.Equal(t, []string{"1s", "1m0s"}, binding.values())
+
+	binding.save()
+	binding.clear(0)
+	require.Equal(t, []time.Duration{}, durations)
+	binding.restore()
+	require.Equal(t, []time.Duration{time.Second, time.Minute}, durations)
+
+	require.Panics(t, func() { Bind(&struct{}{}) })
+}
+
+func TestBindTexts(t *testing.T) {
+	var addrs []net.IP
+
+	binding := BindTexts(&addrs, "[]net.IP")
+	require.Equal(t, "[]net.IP", binding.typ())
+	require.Nil(t, binding.set("10.0.0.1"))
+	require.Equal(t, []net.IP{net.ParseIP("10.0.0.1")}, addrs)
+	require.Equal(t, []string{"10.0.0.1"}, binding.values())
+	require.Equal(t, "invalid IP address: nope", binding.set("nope").Error())
+}
+
+func BenchmarkNewStaticOptionSet(b *testing.B) {
+	key := typeKey{typ: reflect.TypeOf(TestStaticStruct{})}
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			typeCache.Delete(key)
+
+			if _, err := NewOptionSet(&TestStaticStruct{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("static", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			data := TestStaticStruct{}
+
+			if _, err := NewStaticOptionSet("TestStaticStruct", testStaticOptions, testStaticBindings(&data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
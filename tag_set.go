@@ -1,53 +1,233 @@
 package opts
 
-import "strings"
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
 
 type TagSet map[string]string
 
-// Creates a new TagSet from the given raw Tag
+// The tag keys understood by this package
+var knownTags = map[string]bool{
+	"alias":              true,
+	"aliases":            true,
+	"choices":            true,
+	"count":              true,
+	"default":            true,
+	"deprecated":         true,
+	"deprecated-aliases": true,
+	"description":        true,
+	"env":                true,
+	"group":              true,
+	"help":               true,
+	"hidden":             true,
+	"long":               true,
+	"max":                true,
+	"min":                true,
+	"name":               true,
+	"negatable":          true,
+	"pattern":            true,
+	"positional":         true,
+	"reloadable":         true,
+	"required":           true,
+	"secret":             true,
+	"short":              true,
+}
+
+// Creates a new TagSet from the given raw Tag. Malformed tags are ignored from
+// the point the error occurs, use ParseTagSet to get the error.
 func NewTagSet(raw string) TagSet {
+	set, _ := ParseTagSet(raw)
+	return set
+}
+
+// Parses the given raw Tag into a TagSet, following the conventions of
+// reflect.StructTag: key:"value" pairs where values are quoted Go strings.
+// Pairs may be separated by any whitespace, including newlines, and values may
+// span multiple lines. If a key is given more than once the first value is
+// used. Returns the pairs parsed so far and a TagSyntaxError if the tag is
+// malformed.
+func ParseTagSet(raw string) (TagSet, error) {
 	set := TagSet{}
+	pos := 0
+
+	for {
+		// skip the whitespace between pairs
+		for pos < len(raw) && isTagSpace(raw[pos]) {
+			pos++
+		}
+
+		if pos >= len(raw) {
+			return set, nil
+		}
+
+		start := pos
+
+		for pos < len(raw) && raw[pos] > ' ' && raw[pos] != ':' &&
+			raw[pos] != '"' && raw[pos] != 0x7f {
+			pos++
+		}
+
+		if pos == start {
+			return set, &TagSyntaxError{Pos: pos, Msg: "expected a key"}
+		}
+
+		key := raw[start:pos]
+
+		if pos >= len(raw) || raw[pos] != ':' {
+			return set, &TagSyntaxError{
+				Pos: pos,
+				Msg: "expected ':' after key '" + key + "'",
+			}
+		}
 
+		pos++
+
+		if pos >= len(raw) || raw[pos] != '"' {
+			return set, &TagSyntaxError{
+				Pos: pos,
+				Msg: "expected '\"' to start the value of '" + key + "'",
+			}
+		}
+
+		value, end, err := unquoteTagValue(raw, pos)
+
+		if err != nil {
+			return set, err
+		}
+
+		if _, ok := set[key]; !ok {
+			set[key] = value
+		}
+
+		pos = end
+	}
+}
+
+// Parses the value of a namespaced tag into a TagSet. The value is a comma
+// separated list of key=value pairs, i.e. "long=name,short=n,default=foo".
+// Keys without a value are set to "true". Commas and backslashes in values are
+// escaped with a backslash. If a key is given more than once the first value
+// is used, same as ParseTagSet.
+func ParseNamespacedTag(raw string) (TagSet, error) {
+	set := TagSet{}
+	start := 0
 	key := ""
-	in_key := true
+	value := strings.Builder{}
+	inValue := false
 
-	value := ""
-	in_value := false
+	add := func(pos int) error {
+		pair := "true"
 
-	for _, rune := range raw {
-		if (rune == '\n' || rune == '\r') && !in_value {
-			continue
+		if inValue {
+			pair = value.String()
+		} else {
+			key = strings.TrimSpace(value.String())
+
+			if key == "" {
+				return &TagSyntaxError{Pos: start, Msg: "expected a key"}
+			}
 		}
 
-		if rune == ':' && !in_value {
-			in_key = false
-			in_value = false
-			continue
+		if _, ok := set[key]; !ok {
+			set[key] = pair
 		}
 
-		if rune == '"' && !in_value {
-			in_value = true
-			continue
+		start = pos + 1
+		value.Reset()
+		inValue = false
+		return nil
+	}
+
+	for pos := 0; pos < len(raw); pos++ {
+		char := raw[pos]
+
+		switch {
+		case char == '\\' && inValue:
+			if pos+1 >= len(raw) {
+				return set, &TagSyntaxError{Pos: pos, Msg: "invalid escape"}
+			}
+
+			pos++
+			value.WriteByte(raw[pos])
+
+		case char == '=' && !inValue:
+			key = strings.TrimSpace(value.String())
+
+			if key == "" {
+				return set, &TagSyntaxError{Pos: start, Msg: "expected a key"}
+			}
+
+			value.Reset()
+			inValue = true
+
+		case char == ',':
+			if err := add(pos); err != nil {
+				return set, err
+			}
+
+		default:
+			value.WriteByte(char)
+		}
+	}
+
+	if start < len(raw) || inValue {
+		if err := add(len(raw)); err != nil {
+			return set, err
+		}
+	}
+
+	return set, nil
+}
+
+// Returns a copy of the given tags
+func copyTags(tags TagSet) TagSet {
+	copied := make(TagSet, len(tags))
+
+	for key, value := range tags {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// Unquotes the value starting with the quote at the given position of the
+// given raw tag. Returns the value and the position after the closing quote.
+func unquoteTagValue(raw string, start int) (string, int, error) {
+	value := strings.Builder{}
+	pos := start + 1
+
+	for pos < len(raw) {
+		if raw[pos] == '"' {
+			return value.String(), pos + 1, nil
 		}
 
-		if rune == '"' && in_value {
-			trimmed := strings.Trim(strings.Trim(key, " "), "\t")
-			set[trimmed] = value
-			key = ""
-			value = ""
-			in_value = false
-			in_key = true
-			continue
+		char, multibyte, tail, err := strconv.UnquoteChar(raw[pos:], '"')
+
+		if err != nil {
+			return "", pos, &TagSyntaxError{Pos: pos, Msg: "invalid escape"}
 		}
 
-		if in_key {
-			key += string(rune)
+		// byte escapes like \xff are written as is, same as strconv.Unquote
+		if char < utf8.RuneSelf || !multibyte {
+			value.WriteByte(byte(char))
 		} else {
-			value += string(rune)
+			value.WriteRune(char)
 		}
+		pos = len(raw) - len(tail)
 	}
 
-	return set
+	return "", pos, &TagSyntaxError{
+		Pos: start,
+		Msg: "missing closing '\"' for value",
+	}
+}
+
+// Returns true if the given byte separates pairs in a tag
+func isTagSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
 }
 
 // Gets the value defined by the given key, if it exists
@@ -60,3 +240,17 @@ func (this *TagSet) Has(key string) bool {
 	_, ok := (*this)[key]
 	return ok
 }
+
+// Returns the keys of this TagSet which are not understood by this package
+func (this *TagSet) UnknownKeys() []string {
+	keys := []string{}
+
+	for key := range *this {
+		if !knownTags[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -62,3 +62,143 @@ func TestTagSetHas(t *testing.T) {
 	set := NewTagSet(`default:"true"`)
 	require.True(t, set.Has("default"))
 }
+
+func TestParseTagSet_Escapes(t *testing.T) {
+	set, err := ParseTagSet(`description:"Say \"hi\"" path:"C:\\dir" tab:"a\tb"`)
+	require.Nil(t, err)
+	require.Equal(t, `Say "hi"`, set["description"])
+	require.Equal(t, `C:\dir`, set["path"])
+	require.Equal(t, "a\tb", set["tab"])
+}
+
+func TestParseTagSet_ByteEscapes(t *testing.T) {
+	set, err := ParseTagSet(`default:"\xff\u00e9"`)
+	require.Nil(t, err)
+	require.Equal(t, "\xff\u00e9", set["default"])
+}
+
+func TestParseTagSet_FirstValueWins(t *testing.T) {
+	set, err := ParseTagSet(`long:"first" long:"second"`)
+	require.Nil(t, err)
+	require.Equal(t, "first", set["long"])
+}
+
+func TestParseTagSet_Empty(t *testing.T) {
+	set, err := ParseTagSet("  \n\t ")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(set))
+}
+
+func TestParseTagSet_MissingColon(t *testing.T) {
+	set, err := ParseTagSet(`long:"name" short "n"`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 17, err.(*TagSyntaxError).Pos)
+	require.Equal(t,
+		"Invalid tag at position 17: expected ':' after key 'short'",
+		err.Error())
+	require.Equal(t, TagSet{"long": "name"}, set)
+}
+
+func TestParseTagSet_MissingQuote(t *testing.T) {
+	_, err := ParseTagSet(`long:name`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 5, err.(*TagSyntaxError).Pos)
+}
+
+func TestParseTagSet_MissingClosingQuote(t *testing.T) {
+	_, err := ParseTagSet(`long:"name short:"n"`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 19, err.(*TagSyntaxError).Pos)
+}
+
+func TestParseTagSet_Unterminated(t *testing.T) {
+	_, err := ParseTagSet(`long:"name`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 5, err.(*TagSyntaxError).Pos)
+	require.Equal(t,
+		"Invalid tag at position 5: missing closing '\"' for value",
+		err.Error())
+}
+
+func TestParseTagSet_InvalidEscape(t *testing.T) {
+	_, err := ParseTagSet(`long:"na\qme"`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 8, err.(*TagSyntaxError).Pos)
+}
+
+func TestParseTagSet_MissingKey(t *testing.T) {
+	_, err := ParseTagSet(`:"name"`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 0, err.(*TagSyntaxError).Pos)
+}
+
+func TestNewTagSet_Malformed(t *testing.T) {
+	set := NewTagSet(`long:"name" short:n`)
+	require.Equal(t, TagSet{"long": "name"}, set)
+}
+
+func TestTagSetUnknownKeys(t *testing.T) {
+	set := NewTagSet(`long:"name" json:"name" db:"name"`)
+	require.Equal(t, []string{"db", "json"}, set.UnknownKeys())
+}
+
+func TestParseNamespacedTag(t *testing.T) {
+	set, err := ParseNamespacedTag("long=name, short=n,default=foo,env=NAME")
+	require.Nil(t, err)
+	require.Equal(t, TagSet{
+		"default": "foo",
+		"env":     "NAME",
+		"long":    "name",
+		"short":   "n",
+	}, set)
+}
+
+func TestParseNamespacedTag_Flags(t *testing.T) {
+	set, err := ParseNamespacedTag("long=color,negatable,hidden")
+	require.Nil(t, err)
+	require.Equal(t, TagSet{
+		"hidden":    "true",
+		"long":      "color",
+		"negatable": "true",
+	}, set)
+}
+
+func TestParseNamespacedTag_Escapes(t *testing.T) {
+	set, err := ParseNamespacedTag(`default=a\,b\\c,description=x=y,`)
+	require.Nil(t, err)
+	require.Equal(t, `a,b\c`, set["default"])
+	require.Equal(t, "x=y", set["description"])
+	require.Equal(t, 2, len(set))
+}
+
+func TestParseNamespacedTag_Duplicate(t *testing.T) {
+	set, err := ParseNamespacedTag("long=name,long=title,hidden,hidden=false")
+	require.Nil(t, err)
+	require.Equal(t, TagSet{"hidden": "true", "long": "name"}, set)
+
+	set, err = ParseTagSet(`long:"name" long:"title"`)
+	require.Nil(t, err)
+	require.Equal(t, TagSet{"long": "name"}, set)
+}
+
+func TestParseNamespacedTag_EmptyValue(t *testing.T) {
+	set, err := ParseNamespacedTag("default=")
+	require.Nil(t, err)
+	require.Equal(t, TagSet{"default": ""}, set)
+}
+
+func TestParseNamespacedTag_MissingKey(t *testing.T) {
+	_, err := ParseNamespacedTag("long=name,=foo")
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 10, err.(*TagSyntaxError).Pos)
+
+	_, err = ParseNamespacedTag("long=name,,short=n")
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 10, err.(*TagSyntaxError).Pos)
+}
+
+func TestParseNamespacedTag_InvalidEscape(t *testing.T) {
+	_, err := ParseNamespacedTag(`default=foo\`)
+	require.IsType(t, &TagSyntaxError{}, err)
+	require.Equal(t, 11, err.(*TagSyntaxError).Pos)
+}
@@ -0,0 +1,296 @@
+package opts
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// A flag value for counter options. Using the flag without a value increments
+// the count, using it with a value sets the count.
+type counterValue int
+
+func (this *counterValue) Get() interface{} {
+	return int(*this)
+}
+
+func (this *counterValue) IsBoolFlag() bool {
+	return true
+}
+
+func (this *counterValue) Set(raw string) error {
+	switch raw {
+	case "true":
+		*this++
+	case "false":
+		*this = 0
+	default:
+		count, err := strconv.ParseInt(raw, 0, strconv.IntSize)
+
+		if err != nil {
+			return err
+		}
+
+		*this = counterValue(count)
+	}
+
+	return nil
+}
+
+func (this *counterValue) String() string {
+	return strconv.Itoa(int(*this))
+}
+
+// A flag value for negated bool options, stores the inverse of the given value
+type negatedValue bool
+
+func (this *negatedValue) Get() interface{} {
+	return !bool(*this)
+}
+
+func (this *negatedValue) IsBoolFlag() bool {
+	return true
+}
+
+func (this *negatedValue) Set(raw string) error {
+	value, err := strconv.ParseBool(raw)
+
+	if err != nil {
+		return err
+	}
+
+	*this = negatedValue(!value)
+	return nil
+}
+
+func (this *negatedValue) String() string {
+	if this == nil {
+		return "false"
+	}
+
+	return strconv.FormatBool(!bool(*this))
+}
+
+// Returns true if values of the given type can be parsed by setValue. Slices
+// are supported if their elements are.
+func isSupportedType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Slice && !reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return isSupportedScalarType(typ.Elem())
+	}
+
+	return isSupportedScalarType(typ)
+}
+
+// Returns true if single values of the given type can be parsed by setValue
+func isSupportedScalarType(typ reflect.Type) bool {
+	if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool, reflect.Float32, reflect.Float64, reflect.Int,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.String, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// Parses the given raw string and stores it in the value the given pointer
+// points to. Values are appended to slices.
+func setValue(pointer interface{}, raw string) error {
+	value := reflect.ValueOf(pointer)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("Cannot set value of non-pointer")
+	}
+
+	value = value.Elem()
+
+	if value.Kind() == reflect.Slice && !value.Addr().Type().Implements(textUnmarshalerType) {
+		elem := reflect.New(value.Type().Elem())
+		err := setScalarValue(elem.Elem(), raw)
+
+		if err != nil {
+			return err
+		}
+
+		value.Set(reflect.Append(value, elem.Elem()))
+		return nil
+	}
+
+	return setScalarValue(value, raw)
+}
+
+// Parses the given raw value and stores it in the field of this option,
+// appending it to slices
+func (this *Option) store(raw string) error {
+	if this.binding != nil {
+		return this.binding.set(raw)
+	}
+
+	return setValue(this.pointer, raw)
+}
+
+// Parses the given raw string and stores it in the given addressable value
+func setScalarValue(value reflect.Value, raw string) error {
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+
+		if err != nil {
+			return err
+		}
+
+		value.SetBool(parsed)
+
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetFloat(parsed)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 0, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetInt(parsed)
+
+	case reflect.String:
+		value.SetString(raw)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 0, value.Type().Bits())
+
+		if err != nil {
+			return err
+		}
+
+		value.SetUint(parsed)
+
+	default:
+		return errors.New(fmt.Sprintf(
+			"Type '%s' cannot be handled.",
+			value.Type().String()))
+	}
+
+	return nil
+}
+
+// Returns true if values of the given type are formatted as text by
+// formatValue, instead of by kind
+func isTextType(typ reflect.Type) bool {
+	return reflect.PtrTo(typ).Implements(textMarshalerType)
+}
+
+// Formats the given value as a string setValue can parse
+func formatValue(value reflect.Value) string {
+	if isTextType(value.Type()) {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		text, err := ptr.Interface().(encoding.TextMarshaler).MarshalText()
+
+		if err == nil {
+			return string(text)
+		}
+	}
+
+	if value.Type() == durationType {
+		return time.Duration(value.Int()).String()
+	}
+
+	return fmt.Sprint(value.Interface())
+}
+
+// Returns the current value of this option formatted by formatValue, one
+// string for each element of slices
+func (this *Option) formattedValues() []string {
+	if this.binding != nil {
+		return this.binding.values()
+	}
+
+	value := reflect.ValueOf(this.pointer).Elem()
+
+	if value.Kind() != reflect.Slice || isTextType(value.Type()) {
+		return []string{formatValue(value)}
+	}
+
+	values := make([]string, value.Len())
+
+	for n := range values {
+		values[n] = formatValue(value.Index(n))
+	}
+
+	return values
+}
+
+// Returns a copy of the given value which does not share slices with it.
+// Slices held by the value, its slices and its struct fields are copied too.
+func cloneValue(value reflect.Value) reflect.Value {
+	clone := reflect.New(value.Type()).Elem()
+	clone.Set(value)
+	cloneSlices(clone)
+	return clone
+}
+
+// Replaces the slices held by the given value with copies. Struct fields which
+// cannot be set are left as they are.
+func cloneSlices(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.IsNil() {
+			return
+		}
+
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		reflect.Copy(copied, value)
+		value.Set(copied)
+
+		for n := 0; n < copied.Len(); n++ {
+			cloneSlices(copied.Index(n))
+		}
+
+	case reflect.Array:
+		for n := 0; n < value.Len(); n++ {
+			cloneSlices(value.Index(n))
+		}
+
+	case reflect.Struct:
+		for n := 0; n < value.NumField(); n++ {
+			if field := value.Field(n); field.CanSet() {
+				cloneSlices(field)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package opts
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsSupportedType(t *testing.T) {
+	require.True(t, isSupportedType(reflect.TypeOf("")))
+	require.True(t, isSupportedType(reflect.TypeOf(0)))
+	require.True(t, isSupportedType(reflect.TypeOf(time.Second)))
+	require.True(t, isSupportedType(reflect.TypeOf([]int{})))
+	require.True(t, isSupportedType(reflect.TypeOf(net.IP{})))
+	require.True(t, isSupportedType(reflect.TypeOf([]net.IP{})))
+	require.False(t, isSupportedType(reflect.TypeOf(map[string]string{})))
+	require.False(t, isSupportedType(reflect.TypeOf([][]string{})))
+}
+
+func TestSetValue(t *testing.T) {
+	var b bool
+	require.Nil(t, setValue(&b, "true"))
+	require.True(t, b)
+
+	var f float64
+	require.Nil(t, setValue(&f, "3.14"))
+	require.Equal(t, 3.14, f)
+
+	var i int
+	require.Nil(t, setValue(&i, "-10"))
+	require.Equal(t, -10, i)
+
+	var i8 int8
+	require.NotNil(t, setValue(&i8, "300"))
+
+	var s string
+	require.Nil(t, setValue(&s, "foo"))
+	require.Equal(t, "foo", s)
+
+	var u uint64
+	require.Nil(t, setValue(&u, "10"))
+	require.Equal(t, uint64(10), u)
+	require.NotNil(t, setValue(&u, "-10"))
+
+	var d time.Duration
+	require.Nil(t, setValue(&d, "1m30s"))
+	require.Equal(t, 90*time.Second, d)
+	require.NotNil(t, setValue(&d, "soon"))
+}
+
+func TestSetValue_TextUnmarshaler(t *testing.T) {
+	var ip net.IP
+	require.Nil(t, setValue(&ip, "127.0.0.1"))
+	require.Equal(t, "127.0.0.1", ip.String())
+	require.NotNil(t, setValue(&ip, "localhost"))
+}
+
+func TestSetValue_Slice(t *testing.T) {
+	values := []int{}
+	require.Nil(t, setValue(&values, "1"))
+	require.Nil(t, setValue(&values, "2"))
+	require.Equal(t, []int{1, 2}, values)
+	require.NotNil(t, setValue(&values, "three"))
+}
+
+func TestSetValue_Invalid(t *testing.T) {
+	var m map[string]string
+	require.NotNil(t, setValue(&m, "foo"))
+	require.NotNil(t, setValue(m, "foo"))
+}
+
+func TestCounterValue(t *testing.T) {
+	var count int
+	value := (*counterValue)(&count)
+	require.True(t, value.IsBoolFlag())
+	require.Nil(t, value.Set("true"))
+	require.Nil(t, value.Set("true"))
+	require.Equal(t, 2, count)
+	require.Equal(t, "2", value.String())
+	require.Equal(t, 2, value.Get())
+	require.Nil(t, value.Set("5"))
+	require.Equal(t, 5, count)
+	require.Nil(t, value.Set("false"))
+	require.Equal(t, 0, count)
+	require.NotNil(t, value.Set("many"))
+}
+
+func TestNegatedValue(t *testing.T) {
+	value := true
+	negated := (*negatedValue)(&value)
+	require.True(t, negated.IsBoolFlag())
+	require.Equal(t, "false", negated.String())
+	require.Equal(t, false, negated.Get())
+	require.Nil(t, negated.Set("true"))
+	require.False(t, value)
+	require.Nil(t, negated.Set("false"))
+	require.True(t, value)
+	require.NotNil(t, negated.Set("maybe"))
+	require.Equal(t, "false", (*negatedValue)(nil).String())
+}
+
+func TestFormatValue(t *testing.T) {
+	require.Equal(t, "1m30s", formatValue(reflect.ValueOf(90*time.Second)))
+	require.Equal(t, "10.0.0.1", formatValue(reflect.ValueOf(net.ParseIP("10.0.0.1"))))
+	require.Equal(t, "2.5", formatValue(reflect.ValueOf(2.5)))
+	require.Equal(t, "true", formatValue(reflect.ValueOf(true)))
+	require.Equal(t, "foo", formatValue(reflect.ValueOf("foo")))
+}
+
+func TestIsTextType(t *testing.T) {
+	require.True(t, isTextType(reflect.TypeOf(net.IP{})))
+	require.False(t, isTextType(reflect.TypeOf("")))
+}
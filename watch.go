@@ -0,0 +1,309 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// A change of an option value made when reloading
+type Change struct {
+	// the changed option
+	Option *Option
+
+	// the value before the change
+	Old interface{}
+
+	// the value after the change
+	New interface{}
+}
+
+// Returns the change as text, i.e. "--level: info -> debug". Secret values
+// are redacted.
+func (this Change) String() string {
+	if this.Option.Secret {
+		return this.Option.displayName() + ": " + Redacted + " -> " + Redacted
+	}
+
+	return fmt.Sprintf(
+		"%s: %s -> %s",
+		this.Option.displayName(),
+		formatValue(reflect.ValueOf(this.Old)),
+		formatValue(reflect.ValueOf(this.New)))
+}
+
+// Reloads the config file and environment sources of an OptionSet while the
+// program is running. Created by OptionSet.Watch.
+type Watcher struct {
+	// the set being reloaded
+	set *OptionSet
+
+	// the pointer to the copy of the options struct holding the values of
+	// the last reload, never stored for sets created without a struct
+	current atomic.Value
+
+	// serializes reloads
+	lock sync.Mutex
+
+	// the modification times and sizes of the config files, keyed by path
+	stamps map[string]fileStamp
+
+	// the functions called with the changes of each reload
+	subscribers []func(changes []Change)
+
+	// closed to stop watching
+	stop chan struct{}
+
+	// closed when watching stopped
+	done chan struct{}
+}
+
+// The modification time and size of a file, zero if the file does not exist
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Starts reloading the config files and environment variables of this set
+// when the process receives SIGHUP, and when the config files change if the
+// given interval to poll them at is not 0. Values given as flags, positional
+// args or answers to prompts are never reloaded. Options tagged with
+// reloadable:"false" reject changes. Call Close to stop watching.
+//
+// Reloads never change the options struct, which keeps the parsed values and
+// can be read without locking. The reloaded values are written into a copy of
+// the struct, which is swapped in atomically and returned by Watcher.Current.
+// Options added with AddOption are not part of the struct and not reloaded.
+func (this *OptionSet) Watch(interval time.Duration) *Watcher {
+	watcher := Watcher{
+		set:    this,
+		stamps: this.configFileStamps(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	this.lock.RLock()
+	data := this.copyData()
+	this.lock.RUnlock()
+
+	if data != nil {
+		watcher.current.Store(data)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go watcher.run(interval, signals)
+
+	return &watcher
+}
+
+// Reloads on the given signals, and polls the config files at the given
+// interval, until the watcher is closed
+func (this *Watcher) run(interval time.Duration, signals chan os.Signal) {
+	defer close(this.done)
+	defer signal.Stop(signals)
+
+	var ticks <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-this.stop:
+			return
+
+		case <-signals:
+			this.reload()
+
+		case <-ticks:
+			this.lock.Lock()
+			changed := !reflect.DeepEqual(this.stamps, this.set.configFileStamps())
+			this.lock.Unlock()
+
+			if changed {
+				this.reload()
+			}
+		}
+	}
+}
+
+// Reloads, writing errors to the warning output of the set
+func (this *Watcher) reload() {
+	_, err := this.Reload()
+
+	if err != nil {
+		fmt.Fprintf(this.set.warnings, "Cannot reload options: %s\n", err)
+	}
+}
+
+// Stops watching
+func (this *Watcher) Close() {
+	select {
+	case <-this.stop:
+	default:
+		close(this.stop)
+	}
+
+	<-this.done
+}
+
+// Returns a pointer to a copy of the options struct with the values of the
+// last reload, nil for sets created without a struct. Before the first reload
+// it holds the values the struct had when watching started. The copy is
+// replaced, not changed, by reloads, so it must not be changed either.
+func (this *Watcher) Current() interface{} {
+	return this.current.Load()
+}
+
+// Adds a function called with the changes of every reload changing option
+// values
+func (this *Watcher) Subscribe(subscriber func(changes []Change)) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.subscribers = append(this.subscribers, subscriber)
+}
+
+// Reloads the config files and environment variables now. The new values are
+// validated and only set if all of them are valid. Returns the changes made.
+func (this *Watcher) Reload() ([]Change, error) {
+	this.lock.Lock()
+
+	this.stamps = this.set.configFileStamps()
+	changes, data, err := this.set.reload(this.current.Load())
+
+	if err != nil {
+		this.lock.Unlock()
+		return nil, err
+	}
+
+	if data != nil {
+		this.current.Store(data)
+	}
+
+	subscribers := this.subscribers
+	this.lock.Unlock()
+
+	if len(changes) > 0 {
+		for _, subscriber := range subscribers {
+			subscriber(changes)
+		}
+	}
+
+	return changes, nil
+}
+
+// Reads the config files and environment variables of the options not given
+// as flags, positional args or answers to prompts, and writes their values
+// into a copy of the given options struct if all of them are valid. Options
+// which no longer have a config file or environment value are reset to their
+// default. Returns the changes and the copy, nil if there is no struct.
+func (this *OptionSet) reload(current interface{}) ([]Change, interface{}, error) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	changes := []Change{}
+
+	if current == nil {
+		return changes, nil, nil
+	}
+
+	values, err := this.readConfigFiles()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := reflect.New(reflect.TypeOf(current).Elem())
+	data.Elem().Set(cloneValue(reflect.ValueOf(current).Elem()))
+
+	for _, opt := range this.order {
+		if opt.IsPositional() || opt.index == nil || (opt.Source != SourceDefault &&
+			opt.Source != SourceEnv && opt.Source != SourceConfig) {
+			continue
+		}
+
+		field := data.Elem().FieldByIndex(opt.index)
+		next, err := this.reloadedValue(opt, values)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !reflect.DeepEqual(field.Interface(), next.Interface()) {
+			if opt.Tags.Get("reloadable") == "false" {
+				return nil, nil, errors.New(fmt.Sprintf(
+					"Option %s cannot be reloaded",
+					opt.displayName()))
+			}
+
+			changes = append(changes, Change{
+				Option: opt,
+				Old:    field.Interface(),
+				New:    next.Interface(),
+			})
+		}
+
+		field.Set(next)
+	}
+
+	return changes, data.Interface(), nil
+}
+
+// Returns the value of the given option read from its environment variable,
+// or from the given config file values. Returns the default of the option
+// given by its tags or field if it has neither.
+func (this *OptionSet) reloadedValue(opt *Option, values map[*Option]configValue) (reflect.Value, error) {
+	raw := ""
+
+	if env, ok := this.lookupEnv(opt.Env); ok && opt.Env != "" {
+		raw = env
+	} else if value, ok := values[opt]; ok {
+		raw = value.raw
+	} else {
+		return cloneValue(opt.baseDefault), nil
+	}
+
+	next := reflect.New(reflect.TypeOf(opt.pointer).Elem())
+	err := setValue(next.Interface(), raw)
+
+	if err != nil {
+		return next, optionValueError(opt, raw, err)
+	}
+
+	if len(opt.Choices) > 0 && !containsString(opt.Choices, formatValue(next.Elem())) {
+		return next, errors.New(fmt.Sprintf(
+			"Invalid value '%s' for %s, expected one of: %s",
+			formatValue(next.Elem()),
+			opt.displayName(),
+			strings.Join(opt.Choices, ", ")))
+	}
+
+	return next.Elem(), opt.checkLimits(next.Elem())
+}
+
+// Returns the modification times and sizes of the config files of this set
+func (this *OptionSet) configFileStamps() map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+
+	for _, path := range this.configFiles {
+		if info, err := fs.Stat(this.fsys, path); err == nil {
+			stamps[path] = fileStamp{info.ModTime(), info.Size()}
+		} else {
+			stamps[path] = fileStamp{}
+		}
+	}
+
+	return stamps
+}
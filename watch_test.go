@@ -0,0 +1,236 @@
+package opts
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func watchTestLookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func watchTestNewSet(t *testing.T, opts *TestConfigStruct, config string, args ...string) (*OptionSet, string) {
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"), config)
+	set, err := NewOptionSet(opts, WithConfigFile(path), WithWarningOutput(&bytes.Buffer{}))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse(append([]string{}, args...)))
+	return set, path
+}
+
+func TestWatcherReload(t *testing.T) {
+	opts := TestConfigStruct{}
+	set, path := watchTestNewSet(t, &opts, `{"level": "debug", "port": 80}`, "--timeout", "2s")
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	notified := [][]Change{}
+	watcher.Subscribe(func(changes []Change) {
+		notified = append(notified, changes)
+	})
+
+	configTestWrite(t, path, `{"level": "warn", "port": 80, "timeout": "9s", "database": {"db-host": "db"}}`)
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{
+		{Option: set.Options["Level"], Old: "debug", New: "warn"},
+		{Option: set.Options["Database.Host"], Old: "localhost", New: "db"},
+	}, changes)
+	require.Equal(t, [][]Change{changes}, notified)
+	require.Equal(t, "--level: debug -> warn", changes[0].String())
+
+	// the struct keeps the parsed values, the copy has the reloaded ones
+	require.Equal(t, "debug", opts.Level)
+	require.Equal(t, "localhost", opts.Database.Host)
+	require.Equal(t, SourceDefault, set.Options["Database.Host"].Source)
+
+	current := watcher.Current().(*TestConfigStruct)
+	require.Equal(t, "warn", current.Level)
+	require.Equal(t, "db", current.Database.Host)
+	require.Equal(t, 2*time.Second, current.Timeout)
+	require.False(t, current == &opts)
+
+	// reloads without changes do not notify
+	changes, err = watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{}, changes)
+	require.Len(t, notified, 1)
+}
+
+type TestWatchEnvStruct struct {
+	Level string `default:"info" env:"LVL" long:"level"`
+}
+
+func TestWatcherReload_Env(t *testing.T) {
+	env := map[string]string{}
+	data := TestWatchEnvStruct{}
+	set, err := NewOptionSet(&data, WithEnv(watchTestLookupEnv(env)))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	env["LVL"] = "warn"
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{{Option: set.Options["Level"], Old: "info", New: "warn"}}, changes)
+	require.Equal(t, "warn", watcher.Current().(*TestWatchEnvStruct).Level)
+	require.Equal(t, "info", data.Level)
+
+	// unsetting the variable restores the default
+	delete(env, "LVL")
+	changes, err = watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{{Option: set.Options["Level"], Old: "warn", New: "info"}}, changes)
+	require.Equal(t, "info", watcher.Current().(*TestWatchEnvStruct).Level)
+}
+
+func TestWatcherReload_EnvAtConstruction(t *testing.T) {
+	env := map[string]string{"LVL": "warn"}
+	data := TestWatchEnvStruct{}
+	set, err := NewOptionSet(&data, WithEnv(watchTestLookupEnv(env)))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+	require.Equal(t, "warn", data.Level)
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	// the default restored is the tag default, not the value of the variable
+	delete(env, "LVL")
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{{Option: set.Options["Level"], Old: "warn", New: "info"}}, changes)
+	require.Equal(t, "info", watcher.Current().(*TestWatchEnvStruct).Level)
+}
+
+func TestWatcherReload_Removed(t *testing.T) {
+	opts := TestConfigStruct{}
+	set, path := watchTestNewSet(t, &opts, `{"level": "debug", "database": {"db-host": "db"}}`)
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	configTestWrite(t, path, `{}`)
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{
+		{Option: set.Options["Level"], Old: "debug", New: "info"},
+		{Option: set.Options["Database.Host"], Old: "db", New: "localhost"},
+	}, changes)
+
+	current := watcher.Current().(*TestConfigStruct)
+	require.Equal(t, "info", current.Level)
+	require.Equal(t, "localhost", current.Database.Host)
+}
+
+func TestWatcherReload_Invalid(t *testing.T) {
+	tests := map[string]string{
+		`{"level": "trace"}`:                          "Invalid value 'trace' for --level, expected one of: debug, info, warn",
+		`{"timeout": "soon"}`:                         "Invalid value 'soon' for --timeout: time: invalid duration \"soon\"",
+		`{"level": "warn", "port": 90}`:               "Option --port cannot be reloaded",
+		`{"level": "warn", "port": 80, "token": "x"}`: "",
+		`{"level": "warn", "port": 80, "nope": "x"}`:  "Unknown option 'nope'",
+		`{"level": "warn", "port": 80, "token": 1234`: "unexpected EOF",
+		`{"level": "warn"}`:                           "Option --port cannot be reloaded",
+	}
+
+	for config, msg := range tests {
+		opts := TestConfigStruct{}
+		set, path := watchTestNewSet(t, &opts, `{"level": "debug", "port": 80}`)
+		watcher := set.Watch(0)
+
+		configTestWrite(t, path, config)
+		_, err := watcher.Reload()
+		watcher.Close()
+		current := watcher.Current().(*TestConfigStruct)
+
+		if msg == "" {
+			require.Nil(t, err, config)
+			continue
+		}
+
+		require.NotNil(t, err, config)
+		require.Contains(t, err.Error(), msg, config)
+		require.Equal(t, "debug", current.Level, config)
+		require.Equal(t, time.Second, current.Timeout, config)
+	}
+}
+
+func TestWatcherReload_KeepsFlags(t *testing.T) {
+	opts := TestConfigStruct{}
+	set, path := watchTestNewSet(t, &opts, `{}`, "--port", "80", "--level", "debug")
+	watcher := set.Watch(0)
+	defer watcher.Close()
+
+	configTestWrite(t, path, `{"port": 90, "level": "warn"}`)
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{}, changes)
+	require.Equal(t, 80, watcher.Current().(*TestConfigStruct).Port)
+	require.Equal(t, "debug", watcher.Current().(*TestConfigStruct).Level)
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	opts := TestConfigStruct{}
+	set, path := watchTestNewSet(t, &opts, `{"level": "debug"}`)
+	watcher := set.Watch(10 * time.Millisecond)
+	defer watcher.Close()
+
+	notified := make(chan []Change, 1)
+	watcher.Subscribe(func(changes []Change) {
+		notified <- changes
+	})
+
+	configTestWrite(t, path, `{"level": "warn", "timeout": "3s"}`)
+
+	select {
+	case changes := <-notified:
+		require.Len(t, changes, 2)
+		require.Equal(t, "warn", watcher.Current().(*TestConfigStruct).Level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config file change was not reloaded")
+	}
+}
+
+func TestWatcher_PollError(t *testing.T) {
+	reader, writer := io.Pipe()
+	path := configTestWrite(t, filepath.Join(t.TempDir(), "config.json"), `{}`)
+	set, err := NewOptionSet(&TestConfigStruct{}, WithConfigFile(path), WithWarningOutput(writer))
+	require.Nil(t, err)
+	require.Nil(t, set.Parse([]string{}))
+
+	watcher := set.Watch(10 * time.Millisecond)
+	defer watcher.Close()
+	configTestWrite(t, path, `{"level": "warn", "port": 90}`)
+
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, "Cannot reload options: Option --port cannot be reloaded\n", line)
+
+	// the failed reload is not retried until the file changes again
+	go ioutil.ReadAll(reader)
+}
+
+func TestChange_String(t *testing.T) {
+	change := Change{Option: &Option{Long: "timeout"}, Old: time.Second, New: 2 * time.Second}
+	require.Equal(t, "--timeout: 1s -> 2s", change.String())
+	change = Change{Option: &Option{Long: "token", Secret: true}, Old: "foo", New: "bar"}
+	require.Equal(t, "--token: "+Redacted+" -> "+Redacted, change.String())
+}
+
+func TestOptionSetWatch_Empty(t *testing.T) {
+	watcher := NewEmptyOptionSet("empty").Watch(0)
+	defer watcher.Close()
+	require.Nil(t, watcher.Current())
+
+	changes, err := watcher.Reload()
+	require.Nil(t, err)
+	require.Equal(t, []Change{}, changes)
+}